@@ -0,0 +1,143 @@
+package main
+
+import (
+	"errors"
+	"movies-api/internal/context"
+	"movies-api/internal/models"
+	"movies-api/internal/models/acttokens"
+	"movies-api/internal/models/users"
+	"movies-api/internal/utils"
+	"movies-api/internal/validator"
+	"net/http"
+	"time"
+)
+
+// authenticateAPIKey finds owner of api key. Permissions of returned
+// key are already limited to ones owner still has
+func (app *app) authenticateAPIKey(plaintext string) (*users.User, *acttokens.APIKey, error) {
+	v := validator.New()
+
+	if acttokens.ValidateTokenPlaintext(v, plaintext); !v.Valid() {
+		return nil, nil, models.ErrRecordNotFound
+	}
+
+	key, err := app.actTokenService.GetByAPIKey(plaintext)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := app.userService.Get(key.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, key, nil
+}
+
+func (app *app) listAPIKeysHandler(w http.ResponseWriter, r *http.Request) {
+	user := context.ContextGetUser(r)
+
+	keys, err := app.actTokenService.GetAPIKeys(user.Id)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"api_keys": keys}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+// createAPIKeyHandler responds with plaintext of key,
+// it is not possible to get it later
+func (app *app) createAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name        string     `json:"name"`
+		Permissions []string   `json:"permissions"`
+		Expiry      *time.Time `json:"expiry"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	// user of signed token has no MFA state, so it is loaded
+	user, err := app.fullUser(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	ownerPermissions, err := app.grantedPermissions(user)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	key := &acttokens.APIKey{
+		UserID:      user.Id,
+		Name:        input.Name,
+		Permissions: input.Permissions,
+		Expiry:      input.Expiry,
+	}
+
+	v := validator.New()
+
+	if acttokens.ValidateAPIKey(v, key, ownerPermissions); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.actTokenService.NewAPIKey(key)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"api_key": key}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) deleteAPIKeyHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	user := context.ContextGetUser(r)
+
+	err = app.actTokenService.DeleteAPIKey(user.Id, id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "api key successfully deleted"}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
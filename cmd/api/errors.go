@@ -1,14 +1,43 @@
 package main
 
 import (
-	"fmt"
+	"errors"
+	"movies-api/internal/context"
+	"movies-api/internal/i18n"
 	"movies-api/internal/jsonlog"
 	"movies-api/internal/utils"
+	"movies-api/internal/validator"
 	"net/http"
+	"strings"
+)
+
+const (
+	contentTypeProblemJSON = "application/problem+json"
+	problemBaseURI         = "https://moviesapi.net/problems/"
+)
+
+// problem types are part of API contract, so
+// existing values must never change
+const (
+	problemServerError            = "server-error"
+	problemNotFound               = "not-found"
+	problemMethodNotAllowed       = "method-not-allowed"
+	problemBadRequest             = "bad-request"
+	problemUnsupportedMediaType   = "unsupported-media-type"
+	problemFailedValidation       = "failed-validation"
+	problemEditConflict           = "edit-conflict"
+	problemPreconditionFailed     = "precondition-failed"
+	problemRateLimitExceeded      = "rate-limit-exceeded"
+	problemInvalidCredentials     = "invalid-credentials"
+	problemInvalidToken           = "invalid-token"
+	problemAuthenticationRequired = "authentication-required"
+	problemInactiveAccount        = "inactive-account"
+	problemNotPermitted           = "not-permitted"
 )
 
 type CustomError struct {
 	logger *jsonlog.Logger
+	i18n   *i18n.Catalogue
 }
 
 func (e *CustomError) logError(r *http.Request, err error) {
@@ -18,10 +47,49 @@ func (e *CustomError) logError(r *http.Request, err error) {
 	})
 }
 
-func (e *CustomError) errorResponse(w http.ResponseWriter, r *http.Request, status int, msg any) {
-	env := utils.Envelope{"error": msg}
+// t returns message in locale of the request
+func (e *CustomError) t(r *http.Request, key string, params map[string]any) string {
+	return e.i18n.T(context.ContextGetLocale(r), key, params)
+}
+
+// errorResponse writes RFC 7807 problem details. Clients which accept
+// only application/json get legacy {"error": msg} shape instead,
+// where validation errors are reduced to first message of each field
+func (e *CustomError) errorResponse(w http.ResponseWriter, r *http.Request, status int, problemType string, msg any) {
+	var env utils.Envelope
+
+	headers := make(http.Header)
+
+	if errs, ok := msg.(validator.Errors); ok {
+		msg = e.i18n.Errors(context.ContextGetLocale(r), errs)
+	}
+
+	if acceptsLegacyErrors(r) {
+		if errs, ok := msg.(validator.Errors); ok {
+			msg = errs.Messages()
+		}
+
+		env = utils.Envelope{"error": msg}
+	} else {
+		env = utils.Envelope{
+			"type":     problemBaseURI + problemType,
+			"title":    e.t(r, "title."+problemType, nil),
+			"status":   status,
+			"instance": r.URL.Path,
+		}
+
+		switch msg := msg.(type) {
+		case validator.Errors:
+			env["detail"] = e.t(r, "error.failed_validation", nil)
+			env["errors"] = msg
+		default:
+			env["detail"] = msg
+		}
+
+		headers.Set("Content-Type", contentTypeProblemJSON)
+	}
 
-	err := utils.WriteJSON(w, status, env, nil)
+	err := utils.WriteJSON(w, status, env, headers)
 
 	if err != nil {
 		e.logError(r, err)
@@ -29,64 +97,91 @@ func (e *CustomError) errorResponse(w http.ResponseWriter, r *http.Request, stat
 	}
 }
 
+// acceptsLegacyErrors reports whether client asked for plain
+// JSON and did not opt in to problem details
+func acceptsLegacyErrors(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+
+	return strings.Contains(accept, "application/json") && !strings.Contains(accept, contentTypeProblemJSON)
+}
+
 func (e *CustomError) serverErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
 	e.logError(r, err)
 
-	msg := "The server encountered a problem and could not process your request"
-	e.errorResponse(w, r, http.StatusInternalServerError, msg)
+	msg := e.t(r, "error.server_error", nil)
+	e.errorResponse(w, r, http.StatusInternalServerError, problemServerError, msg)
 }
 
 func (e *CustomError) notFoundResponse(w http.ResponseWriter, r *http.Request) {
-	msg := "The requested resource could not be found"
-	e.errorResponse(w, r, http.StatusNotFound, msg)
+	msg := e.t(r, "error.not_found", nil)
+	e.errorResponse(w, r, http.StatusNotFound, problemNotFound, msg)
 }
 
 func (e *CustomError) notAllowedResponse(w http.ResponseWriter, r *http.Request) {
-	msg := fmt.Sprintf("The %s method is not supported for this resource", r.Method)
-	e.errorResponse(w, r, http.StatusMethodNotAllowed, msg)
+	msg := e.t(r, "error.method_not_allowed", map[string]any{"method": r.Method})
+	e.errorResponse(w, r, http.StatusMethodNotAllowed, problemMethodNotAllowed, msg)
 }
 
+// badRequestResponse localizes message of utils.BadRequestError
+// by its code, other errors are written as is
 func (e *CustomError) badRequestResponse(w http.ResponseWriter, r *http.Request, err error) {
-	e.errorResponse(w, r, http.StatusBadRequest, err.Error())
+	msg := err.Error()
+
+	var badRequestErr *utils.BadRequestError
+	if errors.As(err, &badRequestErr) {
+		msg = e.t(r, "error."+badRequestErr.Code, badRequestErr.Params)
+	}
+
+	e.errorResponse(w, r, http.StatusBadRequest, problemBadRequest, msg)
+}
+
+func (e *CustomError) unsupportedMediaTypeResponse(w http.ResponseWriter, r *http.Request, supported ...string) {
+	msg := e.t(r, "error.unsupported_media_type", map[string]any{"types": supported})
+	e.errorResponse(w, r, http.StatusUnsupportedMediaType, problemUnsupportedMediaType, msg)
 }
 
-func (e *CustomError) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors map[string]string) {
-	e.errorResponse(w, r, http.StatusUnprocessableEntity, errors)
+func (e *CustomError) failedValidationResponse(w http.ResponseWriter, r *http.Request, errors validator.Errors) {
+	e.errorResponse(w, r, http.StatusUnprocessableEntity, problemFailedValidation, errors)
 }
 
 func (e *CustomError) editConflictResponse(w http.ResponseWriter, r *http.Request) {
-	msg := "unable to update the record due to an edit conflit, please try again"
-	e.errorResponse(w, r, http.StatusConflict, msg)
+	msg := e.t(r, "error.edit_conflict", nil)
+	e.errorResponse(w, r, http.StatusConflict, problemEditConflict, msg)
+}
+
+func (e *CustomError) preconditionFailedResponse(w http.ResponseWriter, r *http.Request) {
+	msg := e.t(r, "error.precondition_failed", nil)
+	e.errorResponse(w, r, http.StatusPreconditionFailed, problemPreconditionFailed, msg)
 }
 
 func (e *CustomError) rateLimitExceededResponse(w http.ResponseWriter, r *http.Request) {
-	msg := "Too many requests. Please try again in a moment"
-	e.errorResponse(w, r, http.StatusTooManyRequests, msg)
+	msg := e.t(r, "error.rate_limit_exceeded", nil)
+	e.errorResponse(w, r, http.StatusTooManyRequests, problemRateLimitExceeded, msg)
 }
 
 func (e *CustomError) invalidCredentialsResponse(w http.ResponseWriter, r *http.Request) {
-	msg := "invalid credentials"
-	e.errorResponse(w, r, http.StatusForbidden, msg)
+	msg := e.t(r, "error.invalid_credentials", nil)
+	e.errorResponse(w, r, http.StatusForbidden, problemInvalidCredentials, msg)
 }
 
 func (e *CustomError) invalidAuthenticationTokenResponse(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("WWW-Authenticate", "Bearer")
 
-	msg := "invalid authentication token"
-	e.errorResponse(w, r, http.StatusForbidden, msg)
+	msg := e.t(r, "error.invalid_token", nil)
+	e.errorResponse(w, r, http.StatusForbidden, problemInvalidToken, msg)
 }
 
 func (e *CustomError) authenticationRequiredResponse(w http.ResponseWriter, r *http.Request) {
-	message := "you must be authenticated to access this resource"
-	e.errorResponse(w, r, http.StatusUnauthorized, message)
+	message := e.t(r, "error.authentication_required", nil)
+	e.errorResponse(w, r, http.StatusUnauthorized, problemAuthenticationRequired, message)
 }
 
 func (e *CustomError) inactiveAccountResponse(w http.ResponseWriter, r *http.Request) {
-	message := "your user account must be activated to access this resource"
-	e.errorResponse(w, r, http.StatusForbidden, message)
+	message := e.t(r, "error.inactive_account", nil)
+	e.errorResponse(w, r, http.StatusForbidden, problemInactiveAccount, message)
 }
 
 func (e *CustomError) notPermittedResponse(w http.ResponseWriter, r *http.Request) {
-	message := "your user account doesn't have the necessary permissions to access this resource"
-	e.errorResponse(w, r, http.StatusForbidden, message)
+	message := e.t(r, "error.not_permitted", nil)
+	e.errorResponse(w, r, http.StatusForbidden, problemNotPermitted, message)
 }
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"movies-api/internal/models/movies"
+	"movies-api/internal/utils"
+	"movies-api/internal/validator"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rows are flushed to client in batches
+// so export does not sit in buffer
+const exportFlushEvery = 500
+
+func (app *app) exportMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		movies.MovieFilters
+		Format string
+	}
+
+	v := validator.New()
+
+	input.Format = utils.ReadQuery(r.URL.Query(), "format", "csv")
+	input.Title = utils.ReadQuery(r.URL.Query(), "title", "")
+	input.SearchMode = utils.ReadQuery(r.URL.Query(), "search_mode", movies.SearchExact)
+	input.Genres = utils.ReadCSV(r.URL.Query(), "genres", []string{})
+	input.GenresMode = utils.ReadQuery(r.URL.Query(), "genres_mode", "all")
+	input.YearFrom = utils.ReadInt(r.URL.Query(), "year_from", 0, v)
+	input.YearTo = utils.ReadInt(r.URL.Query(), "year_to", 0, v)
+	input.RuntimeMin = utils.ReadInt(r.URL.Query(), "runtime_min", 0, v)
+	input.RuntimeMax = utils.ReadInt(r.URL.Query(), "runtime_max", 0, v)
+	input.PersonId = int64(utils.ReadInt(r.URL.Query(), "person", 0, v))
+	input.Sort = utils.ReadQuery(r.URL.Query(), "sort", "id")
+	input.SortSafelist = []string{"id", "title", "year", "runtime", "rating", "-id", "-title", "-year", "-runtime", "-rating"}
+
+	formats := []string{"csv", "ndjson"}
+	v.CheckCode(validator.AllowedValues(input.Format, formats...), "format", validator.CodeOneOf, validator.Params{"values": formats}, "Format must be csv or ndjson")
+
+	if movies.ValidateFilterCriteria(v, input.MovieFilters); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// export outlives server write timeout, so
+	// this route gets its own deadline
+	rc := http.NewResponseController(w)
+
+	deadline := time.Now().Add(app.config.export.timeout)
+
+	err := rc.SetWriteDeadline(deadline)
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	ctx, cancel := context.WithDeadline(r.Context(), deadline)
+	defer cancel()
+
+	filename := fmt.Sprintf("movies-%s.%s", time.Now().UTC().Format("20060102-150405"), input.Format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	var write func(*movies.Movie) error
+	var flush func() error
+
+	switch input.Format {
+	case "csv":
+		w.Header().Set("Content-Type", contentTypeCSV)
+
+		cw := csv.NewWriter(w)
+
+		write = func(m *movies.Movie) error {
+			return cw.Write([]string{
+				strconv.FormatInt(m.Id, 10),
+				m.Title,
+				strconv.Itoa(int(m.Year)),
+				strconv.Itoa(int(m.Runtime)),
+				strings.Join(m.Genres, ","),
+				strconv.FormatFloat(m.Rating, 'f', -1, 64),
+				strconv.Itoa(int(m.Votes)),
+				strconv.Itoa(int(m.Version)),
+			})
+		}
+
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+
+		err = cw.Write([]string{"id", "title", "year", "runtime", "genres", "rating", "votes", "version"})
+	case "ndjson":
+		w.Header().Set("Content-Type", contentTypeNDJSON)
+
+		enc := json.NewEncoder(w)
+
+		write = func(m *movies.Movie) error {
+			return enc.Encode(m)
+		}
+
+		flush = func() error {
+			return nil
+		}
+	}
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	count := 0
+
+	err = app.movieService.Export(ctx, &input.MovieFilters, func(m *movies.Movie) error {
+		err := write(m)
+		if err != nil {
+			return err
+		}
+
+		count++
+
+		if count%exportFlushEvery == 0 {
+			if err := flush(); err != nil {
+				return err
+			}
+
+			return rc.Flush()
+		}
+
+		return nil
+	})
+
+	if err == nil {
+		err = flush()
+	}
+
+	// nothing was sent yet, so regular error response can be used
+	if err != nil && count == 0 {
+		w.Header().Del("Content-Type")
+		w.Header().Del("Content-Disposition")
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// response is already partially sent, so error
+	// can only be logged and connection dropped
+	if err != nil {
+		app.logger.PrintError(err, map[string]string{
+			"req_method": r.Method,
+			"req_url":    r.URL.String(),
+			"exported":   strconv.Itoa(count),
+		})
+		panic(http.ErrAbortHandler)
+	}
+}
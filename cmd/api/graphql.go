@@ -0,0 +1,309 @@
+package main
+
+import (
+	"fmt"
+	"movies-api/internal/context"
+	"movies-api/internal/utils"
+	"movies-api/internal/validator"
+	"net/http"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// graphqlListSize is expected number of items of list fields
+// without pageSize argument, used to estimate query complexity.
+// Fields with pageSize argument use its default from schema
+var graphqlListSize = map[string]int{
+	"credits": 10,
+}
+
+// graphqlError is resolver error with same problem type
+// as REST API returns for the failure
+type graphqlError struct {
+	problemType string
+	message     string
+	errors      validator.Errors
+}
+
+func (e graphqlError) Error() string {
+	return e.message
+}
+
+func (e graphqlError) Extensions() map[string]any {
+	ext := map[string]any{"type": problemBaseURI + e.problemType}
+
+	if e.errors != nil {
+		ext["errors"] = e.errors
+	}
+
+	return ext
+}
+
+func (app *app) graphqlHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Query         string         `json:"query"`
+		OperationName string         `json:"operationName"`
+		Variables     map[string]any `json:"variables"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	var result *graphql.Result
+
+	if err := app.checkGraphQLLimits(input.Query, input.Variables); err != nil {
+		formatted := gqlerrors.FormatError(&gqlerrors.Error{Message: err.Error(), OriginalError: err})
+		result = &graphql.Result{Errors: []gqlerrors.FormattedError{formatted}}
+	} else {
+		result = graphql.Do(graphql.Params{
+			Schema:         app.graphqlSchema,
+			RequestString:  input.Query,
+			OperationName:  input.OperationName,
+			VariableValues: input.Variables,
+			// resolvers get request to check user and permissions
+			RootObject: map[string]any{"request": r},
+			Context:    r.Context(),
+		})
+	}
+
+	env := utils.Envelope{"data": result.Data}
+
+	if result.HasErrors() {
+		env["errors"] = result.Errors
+	}
+
+	// errors are part of GraphQL response, so status is always OK
+	err = utils.WriteJSON(w, http.StatusOK, env, nil)
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+// checkGraphQLLimits rejects queries which are nested deeper or
+// cost more than configured limits. Invalid queries are left
+// for graphql.Do, which reports syntax errors itself
+func (app *app) checkGraphQLLimits(query string, variables map[string]any) error {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return nil
+	}
+
+	fragments := make(map[string]*ast.FragmentDefinition)
+
+	for _, def := range doc.Definitions {
+		if fragment, ok := def.(*ast.FragmentDefinition); ok {
+			fragments[fragment.Name.Value] = fragment
+		}
+	}
+
+	for _, def := range doc.Definitions {
+		operation, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+
+		var root graphql.Type = app.graphqlSchema.QueryType()
+
+		if operation.Operation == ast.OperationTypeMutation {
+			root = app.graphqlSchema.MutationType()
+		}
+
+		c := graphqlCost{schema: app.graphqlSchema, fragments: fragments, variables: variables, visiting: make(map[string]bool)}
+		depth, complexity := c.selectionSet(operation.SelectionSet, root)
+
+		if depth > app.config.graphql.maxDepth {
+			return graphqlError{
+				problemType: problemBadRequest,
+				message:     fmt.Sprintf("query depth %d exceeds maximum depth %d", depth, app.config.graphql.maxDepth),
+			}
+		}
+
+		if complexity > app.config.graphql.maxComplexity {
+			return graphqlError{
+				problemType: problemBadRequest,
+				message:     fmt.Sprintf("query complexity %d exceeds maximum complexity %d", complexity, app.config.graphql.maxComplexity),
+			}
+		}
+	}
+
+	return nil
+}
+
+// graphqlCost walks selections of operation with fragments inlined
+type graphqlCost struct {
+	schema    graphql.Schema
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]any
+	// fragments on current path, cycles are reported by graphql.Do
+	visiting map[string]bool
+}
+
+// selectionSet returns depth and complexity of selections of parent type.
+// Each field costs 1, selections of list fields are counted once per item
+func (c graphqlCost) selectionSet(set *ast.SelectionSet, parent graphql.Type) (int, int) {
+	if set == nil {
+		return 0, 0
+	}
+
+	depth, complexity := 0, 0
+
+	for _, selection := range set.Selections {
+		var d, n int
+
+		switch selection := selection.(type) {
+		case *ast.Field:
+			def := c.fieldDefinition(parent, selection.Name.Value)
+
+			var fieldType graphql.Type
+			if def != nil {
+				fieldType = def.Type
+			}
+
+			d, n = c.selectionSet(selection.SelectionSet, fieldType)
+			d, n = d+1, 1+n*c.listSize(selection, def)
+		case *ast.InlineFragment:
+			fragmentType := parent
+
+			if selection.TypeCondition != nil {
+				fragmentType = c.schema.Type(selection.TypeCondition.Name.Value)
+			}
+
+			d, n = c.selectionSet(selection.SelectionSet, fragmentType)
+		case *ast.FragmentSpread:
+			name := selection.Name.Value
+			fragment, ok := c.fragments[name]
+
+			if !ok || c.visiting[name] {
+				continue
+			}
+
+			c.visiting[name] = true
+			d, n = c.selectionSet(fragment.SelectionSet, c.schema.Type(fragment.TypeCondition.Name.Value))
+			delete(c.visiting, name)
+		}
+
+		if d > depth {
+			depth = d
+		}
+
+		complexity += n
+	}
+
+	return depth, complexity
+}
+
+// fieldDefinition returns definition of field of parent type,
+// nil is returned for unknown fields which are reported by graphql.Do
+func (c graphqlCost) fieldDefinition(parent graphql.Type, name string) *graphql.FieldDefinition {
+	if parent == nil {
+		return nil
+	}
+
+	fieldsHaver, ok := graphql.GetNamed(parent).(interface {
+		Fields() graphql.FieldDefinitionMap
+	})
+	if !ok {
+		return nil
+	}
+
+	return fieldsHaver.Fields()[name]
+}
+
+// listSize returns pageSize of field, its default from
+// schema when argument is omitted or can not be read
+func (c graphqlCost) listSize(field *ast.Field, def *graphql.FieldDefinition) int {
+	for _, arg := range field.Arguments {
+		if arg.Name.Value != "pageSize" {
+			continue
+		}
+
+		switch value := arg.Value.(type) {
+		case *ast.IntValue:
+			if size, err := strconv.Atoi(value.Value); err == nil {
+				return size
+			}
+		case *ast.Variable:
+			if size, ok := c.variables[value.Name.Value].(float64); ok {
+				return int(size)
+			}
+		}
+	}
+
+	if def != nil {
+		for _, arg := range def.Args {
+			if arg.Name() != "pageSize" {
+				continue
+			}
+
+			if size, ok := arg.DefaultValue.(int); ok {
+				return size
+			}
+		}
+	}
+
+	if size, ok := graphqlListSize[field.Name.Value]; ok {
+		return size
+	}
+
+	return 1
+}
+
+// graphqlRequest returns request passed as root value to resolvers
+func graphqlRequest(p graphql.ResolveParams) *http.Request {
+	return p.Info.RootValue.(map[string]any)["request"].(*http.Request)
+}
+
+// graphqlAuthorize applies same checks as requirePermission middleware.
+// Empty code only requires authenticated user
+func (app *app) graphqlAuthorize(r *http.Request, code string) error {
+	user := context.ContextGetUser(r)
+
+	if user.IsAnon() {
+		return graphqlError{problemType: problemAuthenticationRequired, message: app.err.t(r, "error.authentication_required", nil)}
+	}
+
+	if code == "" {
+		return nil
+	}
+
+	if !user.Activated {
+		return graphqlError{problemType: problemInactiveAccount, message: app.err.t(r, "error.inactive_account", nil)}
+	}
+
+	permissions, err := app.userPermissions(r)
+	if err != nil {
+		return app.graphqlServerError(r, err)
+	}
+
+	if !permissions.IsInclude(code) {
+		return graphqlError{problemType: problemNotPermitted, message: app.err.t(r, "error.not_permitted", nil)}
+	}
+
+	return nil
+}
+
+// graphqlServerError logs err and hides its details from client
+func (app *app) graphqlServerError(r *http.Request, err error) error {
+	app.err.logError(r, err)
+
+	return graphqlError{problemType: problemServerError, message: app.err.t(r, "error.server_error", nil)}
+}
+
+func (app *app) graphqlEditConflict(r *http.Request) error {
+	return graphqlError{problemType: problemEditConflict, message: app.err.t(r, "error.edit_conflict", nil)}
+}
+
+func (app *app) graphqlValidationError(r *http.Request, errs validator.Errors) error {
+	return graphqlError{
+		problemType: problemFailedValidation,
+		message:     app.err.t(r, "error.failed_validation", nil),
+		errors:      app.i18n.Errors(context.ContextGetLocale(r), errs),
+	}
+}
@@ -0,0 +1,539 @@
+package main
+
+import (
+	"errors"
+	"movies-api/internal/context"
+	"movies-api/internal/models"
+	"movies-api/internal/models/movies"
+	"movies-api/internal/models/people"
+	"movies-api/internal/models/reviews"
+	"movies-api/internal/models/users"
+	"movies-api/internal/validator"
+	"strconv"
+
+	"github.com/graphql-go/graphql"
+)
+
+// newGraphQLSchema builds schema over movie and user services.
+// Field names follow GraphQL camelCase convention
+func (app *app) newGraphQLSchema() (graphql.Schema, error) {
+	metadataType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Metadata",
+		Fields: graphql.Fields{
+			"currentPage":  &graphql.Field{Type: graphql.Int, Resolve: resolveMetadata(func(m models.Metadata) any { return m.CurrentPage })},
+			"pageSize":     &graphql.Field{Type: graphql.Int, Resolve: resolveMetadata(func(m models.Metadata) any { return m.PageSize })},
+			"firstPage":    &graphql.Field{Type: graphql.Int, Resolve: resolveMetadata(func(m models.Metadata) any { return m.FirstPage })},
+			"lastPage":     &graphql.Field{Type: graphql.Int, Resolve: resolveMetadata(func(m models.Metadata) any { return m.LastPage })},
+			"totalRecords": &graphql.Field{Type: graphql.Int, Resolve: resolveMetadata(func(m models.Metadata) any { return m.TotalRecords })},
+			"nextCursor":   &graphql.Field{Type: graphql.String, Resolve: resolveMetadata(func(m models.Metadata) any { return m.NextCursor })},
+		},
+	})
+
+	creditType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Credit",
+		Fields: graphql.Fields{
+			"movieId":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: resolveCredit(func(c *people.Credit) any { return c.MovieId })},
+			"personId":     &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: resolveCredit(func(c *people.Credit) any { return c.PersonId })},
+			"name":         &graphql.Field{Type: graphql.String, Resolve: resolveCredit(func(c *people.Credit) any { return c.Name })},
+			"role":         &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveCredit(func(c *people.Credit) any { return c.Role })},
+			"character":    &graphql.Field{Type: graphql.String, Resolve: resolveCredit(func(c *people.Credit) any { return c.Character })},
+			"billingOrder": &graphql.Field{Type: graphql.Int, Resolve: resolveCredit(func(c *people.Credit) any { return c.BillingOrder })},
+		},
+	})
+
+	reviewType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Review",
+		Fields: graphql.Fields{
+			"id":        &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: resolveReview(func(r *reviews.Review) any { return r.Id })},
+			"userId":    &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: resolveReview(func(r *reviews.Review) any { return r.UserId })},
+			"rating":    &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: resolveReview(func(r *reviews.Review) any { return r.Rating })},
+			"body":      &graphql.Field{Type: graphql.String, Resolve: resolveReview(func(r *reviews.Review) any { return r.Body })},
+			"createdAt": &graphql.Field{Type: graphql.DateTime, Resolve: resolveReview(func(r *reviews.Review) any { return r.CreatedAt })},
+		},
+	})
+
+	movieType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Movie",
+		Fields: graphql.Fields{
+			"id":      &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: resolveMovie(func(m *movies.Movie) any { return m.Id })},
+			"title":   &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: resolveMovie(func(m *movies.Movie) any { return m.Title })},
+			"year":    &graphql.Field{Type: graphql.Int, Resolve: resolveMovie(func(m *movies.Movie) any { return m.Year })},
+			"runtime": &graphql.Field{Type: graphql.Int, Resolve: resolveMovie(func(m *movies.Movie) any { return m.Runtime })},
+			"genres":  &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(graphql.String)), Resolve: resolveMovie(func(m *movies.Movie) any { return m.Genres })},
+			"rating":  &graphql.Field{Type: graphql.Float, Resolve: resolveMovie(func(m *movies.Movie) any { return m.Rating })},
+			"votes":   &graphql.Field{Type: graphql.Int, Resolve: resolveMovie(func(m *movies.Movie) any { return m.Votes })},
+			"watched": &graphql.Field{Type: graphql.Boolean, Resolve: resolveMovie(func(m *movies.Movie) any { return m.Watched })},
+			"version": &graphql.Field{Type: graphql.NewNonNull(graphql.Int), Resolve: resolveMovie(func(m *movies.Movie) any { return m.Version })},
+			"credits": &graphql.Field{
+				Type:    graphql.NewList(graphql.NewNonNull(creditType)),
+				Resolve: app.resolveMovieCredits,
+			},
+			"reviews": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(reviewType)),
+				Args: graphql.FieldConfigArgument{
+					"page":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+					"pageSize": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 10},
+				},
+				Resolve: app.resolveMovieReviews,
+			},
+		},
+	})
+
+	moviesPageType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "MoviesPage",
+		Fields: graphql.Fields{
+			"items":    &graphql.Field{Type: graphql.NewList(graphql.NewNonNull(movieType))},
+			"metadata": &graphql.Field{Type: metadataType},
+		},
+	})
+
+	userType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "User",
+		Fields: graphql.Fields{
+			"id":         &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: resolveUser(func(u *users.User) any { return u.Id })},
+			"name":       &graphql.Field{Type: graphql.String, Resolve: resolveUser(func(u *users.User) any { return u.Name })},
+			"email":      &graphql.Field{Type: graphql.String, Resolve: resolveUser(func(u *users.User) any { return u.Email })},
+			"activated":  &graphql.Field{Type: graphql.Boolean, Resolve: resolveUser(func(u *users.User) any { return u.Activated })},
+			"locale":     &graphql.Field{Type: graphql.String, Resolve: resolveUser(func(u *users.User) any { return u.Locale })},
+			"mfaEnabled": &graphql.Field{Type: graphql.Boolean, Resolve: resolveUser(func(u *users.User) any { return u.MFAEnabled })},
+			"createdAt":  &graphql.Field{Type: graphql.DateTime, Resolve: resolveUser(func(u *users.User) any { return u.Created_at })},
+			"permissions": &graphql.Field{
+				Type:    graphql.NewList(graphql.NewNonNull(graphql.String)),
+				Resolve: app.resolveUserPermissions,
+			},
+		},
+	})
+
+	movieInputFields := func(required bool) graphql.InputObjectConfigFieldMap {
+		wrap := func(t graphql.Input) graphql.Input {
+			if required {
+				return graphql.NewNonNull(t)
+			}
+			return t
+		}
+
+		return graphql.InputObjectConfigFieldMap{
+			"title":   &graphql.InputObjectFieldConfig{Type: wrap(graphql.String)},
+			"year":    &graphql.InputObjectFieldConfig{Type: wrap(graphql.Int)},
+			"runtime": &graphql.InputObjectFieldConfig{Type: wrap(graphql.Int)},
+			"genres":  &graphql.InputObjectFieldConfig{Type: wrap(graphql.NewList(graphql.NewNonNull(graphql.String)))},
+		}
+	}
+
+	createMovieInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:   "CreateMovieInput",
+		Fields: movieInputFields(true),
+	})
+
+	updateMovieInput := graphql.NewInputObject(graphql.InputObjectConfig{
+		Name:   "UpdateMovieInput",
+		Fields: movieInputFields(false),
+	})
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"movies": &graphql.Field{
+				Type: graphql.NewNonNull(moviesPageType),
+				Args: graphql.FieldConfigArgument{
+					"title":      &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: ""},
+					"searchMode": &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: movies.SearchExact},
+					"genres":     &graphql.ArgumentConfig{Type: graphql.NewList(graphql.NewNonNull(graphql.String))},
+					"genresMode": &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "all"},
+					"yearFrom":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+					"yearTo":     &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+					"runtimeMin": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+					"runtimeMax": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+					"person":     &graphql.ArgumentConfig{Type: graphql.ID},
+					"page":       &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 1},
+					"pageSize":   &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 10},
+					"cursor":     &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: ""},
+					"withTotal":  &graphql.ArgumentConfig{Type: graphql.Boolean},
+					"sort":       &graphql.ArgumentConfig{Type: graphql.String, DefaultValue: "id"},
+				},
+				Resolve: app.resolveMovies,
+			},
+			"movie": &graphql.Field{
+				Type:    movieType,
+				Args:    graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}},
+				Resolve: app.resolveMovie,
+			},
+			"me": &graphql.Field{
+				Type:    graphql.NewNonNull(userType),
+				Resolve: app.resolveMe,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createMovie": &graphql.Field{
+				Type:    graphql.NewNonNull(movieType),
+				Args:    graphql.FieldConfigArgument{"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createMovieInput)}},
+				Resolve: app.resolveCreateMovie,
+			},
+			"updateMovie": &graphql.Field{
+				Type: graphql.NewNonNull(movieType),
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateMovieInput)},
+					// version works as If-Match of REST API
+					"version": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: app.resolveUpdateMovie,
+			},
+			"deleteMovie": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.Boolean),
+				Args: graphql.FieldConfigArgument{
+					"id":      &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"version": &graphql.ArgumentConfig{Type: graphql.Int},
+				},
+				Resolve: app.resolveDeleteMovie,
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func resolveMetadata(fn func(models.Metadata) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		return fn(p.Source.(models.Metadata)), nil
+	}
+}
+
+func resolveCredit(fn func(*people.Credit) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		return fn(p.Source.(*people.Credit)), nil
+	}
+}
+
+func resolveReview(fn func(*reviews.Review) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		return fn(p.Source.(*reviews.Review)), nil
+	}
+}
+
+func resolveMovie(fn func(*movies.Movie) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		return fn(p.Source.(*movies.Movie)), nil
+	}
+}
+
+func resolveUser(fn func(*users.User) any) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (any, error) {
+		return fn(p.Source.(*users.User)), nil
+	}
+}
+
+// graphqlId parses ID argument, ids which are not
+// positive integers can not match any record
+func graphqlId(p graphql.ResolveParams, name string) (int64, bool) {
+	s, _ := p.Args[name].(string)
+
+	id, err := strconv.ParseInt(s, 10, 64)
+
+	return id, err == nil && id > 0
+}
+
+func (app *app) graphqlNotFound(p graphql.ResolveParams) error {
+	r := graphqlRequest(p)
+
+	return graphqlError{problemType: problemNotFound, message: app.err.t(r, "error.not_found", nil)}
+}
+
+func (app *app) resolveMovies(p graphql.ResolveParams) (any, error) {
+	r := graphqlRequest(p)
+
+	if err := app.graphqlAuthorize(r, "movies:read"); err != nil {
+		return nil, err
+	}
+
+	f := movies.MovieFilters{
+		Title:      p.Args["title"].(string),
+		SearchMode: p.Args["searchMode"].(string),
+		Genres:     []string{},
+		GenresMode: p.Args["genresMode"].(string),
+		YearFrom:   p.Args["yearFrom"].(int),
+		YearTo:     p.Args["yearTo"].(int),
+		RuntimeMin: p.Args["runtimeMin"].(int),
+		RuntimeMax: p.Args["runtimeMax"].(int),
+		UserId:     context.ContextGetUser(r).Id,
+		Page:       p.Args["page"].(int),
+		PageSize:   p.Args["pageSize"].(int),
+		Cursor:     p.Args["cursor"].(string),
+		Sort:       p.Args["sort"].(string),
+		SortSafelist: []string{
+			"id", "title", "year", "runtime", "rating",
+			"-id", "-title", "-year", "-runtime", "-rating", "relevance",
+		},
+	}
+
+	if genres, ok := p.Args["genres"].([]any); ok {
+		for _, genre := range genres {
+			f.Genres = append(f.Genres, genre.(string))
+		}
+	}
+
+	v := validator.New()
+
+	if _, ok := p.Args["person"]; ok {
+		id, ok := graphqlId(p, "person")
+		v.CheckCode(ok, "person", validator.CodeMin, validator.Params{"min": 1}, "Person must be a positive id")
+		f.PersonId = id
+	}
+
+	// total is skipped by default in cursor mode
+	f.WithTotal = f.Cursor == ""
+
+	if withTotal, ok := p.Args["withTotal"].(bool); ok {
+		f.WithTotal = withTotal
+	}
+
+	if movies.ValidateFilters(v, f); !v.Valid() {
+		return nil, app.graphqlValidationError(r, v.Errors)
+	}
+
+	list, meta, err := app.movieService.GetAll(&f)
+	if err != nil {
+		return nil, app.graphqlServerError(r, err)
+	}
+
+	return map[string]any{"items": list, "metadata": meta}, nil
+}
+
+func (app *app) resolveMovie(p graphql.ResolveParams) (any, error) {
+	r := graphqlRequest(p)
+
+	if err := app.graphqlAuthorize(r, "movies:read"); err != nil {
+		return nil, err
+	}
+
+	id, ok := graphqlId(p, "id")
+	if !ok {
+		return nil, app.graphqlNotFound(p)
+	}
+
+	movie, err := app.movieService.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			return nil, app.graphqlNotFound(p)
+		default:
+			return nil, app.graphqlServerError(r, err)
+		}
+	}
+
+	return movie, nil
+}
+
+func (app *app) resolveMovieCredits(p graphql.ResolveParams) (any, error) {
+	movie := p.Source.(*movies.Movie)
+
+	if movie.Credits != nil {
+		return movie.Credits, nil
+	}
+
+	credits, err := app.peopleService.GetCreditsForMovie(movie.Id)
+	if err != nil {
+		return nil, app.graphqlServerError(graphqlRequest(p), err)
+	}
+
+	return credits, nil
+}
+
+func (app *app) resolveMovieReviews(p graphql.ResolveParams) (any, error) {
+	r := graphqlRequest(p)
+	movie := p.Source.(*movies.Movie)
+
+	f := reviews.ReviewFilters{
+		Page:     p.Args["page"].(int),
+		PageSize: p.Args["pageSize"].(int),
+	}
+
+	v := validator.New()
+
+	if reviews.ValidateFilters(v, f); !v.Valid() {
+		return nil, app.graphqlValidationError(r, v.Errors)
+	}
+
+	list, _, err := app.reviewService.GetAllForMovie(movie.Id, &f)
+	if err != nil {
+		return nil, app.graphqlServerError(r, err)
+	}
+
+	return list, nil
+}
+
+func (app *app) resolveMe(p graphql.ResolveParams) (any, error) {
+	r := graphqlRequest(p)
+
+	if err := app.graphqlAuthorize(r, ""); err != nil {
+		return nil, err
+	}
+
+	user, err := app.fullUser(r)
+	if err != nil {
+		return nil, app.graphqlServerError(r, err)
+	}
+
+	return user, nil
+}
+
+func (app *app) resolveUserPermissions(p graphql.ResolveParams) (any, error) {
+	r := graphqlRequest(p)
+
+	// same as REST, permissions are narrowed to ones
+	// of the token or api key request is made with
+	permissions, err := app.userPermissions(r)
+	if err != nil {
+		return nil, app.graphqlServerError(r, err)
+	}
+
+	return []string(permissions), nil
+}
+
+func (app *app) resolveCreateMovie(p graphql.ResolveParams) (any, error) {
+	r := graphqlRequest(p)
+
+	if err := app.graphqlAuthorize(r, "movies:write"); err != nil {
+		return nil, err
+	}
+
+	input := p.Args["input"].(map[string]any)
+
+	movie := &movies.Movie{}
+	applyMovieInput(movie, input)
+
+	v := validator.New()
+
+	if movies.ValidateMovie(v, movie); !v.Valid() {
+		return nil, app.graphqlValidationError(r, v.Errors)
+	}
+
+	err := app.movieService.Create(movie, context.ContextGetUser(r).Id)
+	if err != nil {
+		return nil, app.graphqlServerError(r, err)
+	}
+
+	return movie, nil
+}
+
+func (app *app) resolveUpdateMovie(p graphql.ResolveParams) (any, error) {
+	r := graphqlRequest(p)
+
+	if err := app.graphqlAuthorize(r, "movies:write"); err != nil {
+		return nil, err
+	}
+
+	id, ok := graphqlId(p, "id")
+	if !ok {
+		return nil, app.graphqlNotFound(p)
+	}
+
+	movie, err := app.movieService.Get(id)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			return nil, app.graphqlNotFound(p)
+		default:
+			return nil, app.graphqlServerError(r, err)
+		}
+	}
+
+	// client can only update the version it has seen
+	if version, ok := p.Args["version"].(int); ok && int32(version) != movie.Version {
+		return nil, app.graphqlEditConflict(r)
+	}
+
+	applyMovieInput(movie, p.Args["input"].(map[string]any))
+
+	v := validator.New()
+
+	if movies.ValidateMovie(v, movie); !v.Valid() {
+		return nil, app.graphqlValidationError(r, v.Errors)
+	}
+
+	err = app.movieService.Update(movie, context.ContextGetUser(r).Id)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrEditConflict):
+			return nil, app.graphqlEditConflict(r)
+		default:
+			return nil, app.graphqlServerError(r, err)
+		}
+	}
+
+	return movie, nil
+}
+
+func (app *app) resolveDeleteMovie(p graphql.ResolveParams) (any, error) {
+	r := graphqlRequest(p)
+
+	if err := app.graphqlAuthorize(r, "movies:write"); err != nil {
+		return nil, err
+	}
+
+	id, ok := graphqlId(p, "id")
+	if !ok {
+		return nil, app.graphqlNotFound(p)
+	}
+
+	// zero version deletes movie regardless of its version
+	version, _ := p.Args["version"].(int)
+
+	if version != 0 {
+		movie, err := app.movieService.Get(id)
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrRecordNotFound):
+				return nil, app.graphqlNotFound(p)
+			default:
+				return nil, app.graphqlServerError(r, err)
+			}
+		}
+
+		if movie.Version != int32(version) {
+			return nil, app.graphqlEditConflict(r)
+		}
+	}
+
+	err := app.movieService.Delete(id, int32(version))
+	if err != nil {
+		switch {
+		// movie was changed after its version was checked
+		case errors.Is(err, models.ErrRecordNotFound) && version != 0:
+			return nil, app.graphqlEditConflict(r)
+		case errors.Is(err, models.ErrRecordNotFound):
+			return nil, app.graphqlNotFound(p)
+		default:
+			return nil, app.graphqlServerError(r, err)
+		}
+	}
+
+	return true, nil
+}
+
+// applyMovieInput copies fields present in input to movie
+func applyMovieInput(movie *movies.Movie, input map[string]any) {
+	if title, ok := input["title"].(string); ok {
+		movie.Title = title
+	}
+
+	if year, ok := input["year"].(int); ok {
+		movie.Year = int32(year)
+	}
+
+	if runtime, ok := input["runtime"].(int); ok {
+		movie.Runtime = int32(runtime)
+	}
+
+	if genres, ok := input["genres"].([]any); ok {
+		movie.Genres = make([]string, 0, len(genres))
+
+		for _, genre := range genres {
+			movie.Genres = append(movie.Genres, genre.(string))
+		}
+	}
+}
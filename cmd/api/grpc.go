@@ -0,0 +1,253 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	appcontext "movies-api/internal/context"
+	"movies-api/internal/models"
+	"movies-api/internal/models/acttokens"
+	"movies-api/internal/models/users"
+	"movies-api/internal/pb"
+	"movies-api/internal/signedtokens"
+	"movies-api/internal/validator"
+	"sort"
+	"strings"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// grpcPermissions maps gRPC methods to permission they require,
+// methods missing here are available without authentication
+var grpcPermissions = map[string]string{
+	pb.MoviesService_Get_FullMethodName:    "movies:read",
+	pb.MoviesService_List_FullMethodName:   "movies:read",
+	pb.MoviesService_Create_FullMethodName: "movies:write",
+	pb.MoviesService_Update_FullMethodName: "movies:write",
+	pb.MoviesService_Delete_FullMethodName: "movies:write",
+}
+
+func (app *app) newGRPCServer() *grpc.Server {
+	limiter := newClientLimiter(app.config.limiter.rps, app.config.limiter.burst)
+
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(app.grpcRecoverUnary, app.grpcRateLimitUnary(limiter), app.grpcAuthenticateUnary),
+		grpc.ChainStreamInterceptor(app.grpcRecoverStream, app.grpcRateLimitStream(limiter), app.grpcAuthenticateStream),
+	)
+
+	pb.RegisterMoviesServiceServer(server, &moviesServer{app: app})
+	pb.RegisterAuthServiceServer(server, &authServer{app: app})
+
+	return server
+}
+
+func (app *app) grpcRecoverUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = app.grpcServerError(ctx, fmt.Errorf("%s", p))
+		}
+	}()
+
+	return handler(ctx, req)
+}
+
+func (app *app) grpcRecoverStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = app.grpcServerError(ss.Context(), fmt.Errorf("%s", p))
+		}
+	}()
+
+	return handler(srv, ss)
+}
+
+func (app *app) grpcRateLimitUnary(limiter *clientLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if err := app.grpcRateLimit(ctx, limiter); err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func (app *app) grpcRateLimitStream(limiter *clientLimiter) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := app.grpcRateLimit(ss.Context(), limiter); err != nil {
+			return err
+		}
+
+		return handler(srv, ss)
+	}
+}
+
+// grpcRateLimit applies same per client limit as rateLimit
+// middleware does, client is identified by its peer address
+func (app *app) grpcRateLimit(ctx context.Context, limiter *clientLimiter) error {
+	if !app.config.limiter.enabled {
+		return nil
+	}
+
+	if !limiter.Allow(grpcPeerIP(ctx)) {
+		return status.Error(codes.ResourceExhausted, app.grpcT(ctx, "error.rate_limit_exceeded", nil))
+	}
+
+	return nil
+}
+
+func (app *app) grpcAuthenticateUnary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := app.grpcAuthenticate(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+
+	return handler(ctx, req)
+}
+
+func (app *app) grpcAuthenticateStream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := app.grpcAuthenticate(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+
+	return handler(srv, &grpcServerStream{ServerStream: ss, ctx: ctx})
+}
+
+// grpcServerStream replaces context of stream with
+// one which holds authenticated user
+type grpcServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *grpcServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// grpcAuthenticate does what authenticate and requirePermission
+// middlewares do for REST API. Credentials are read from "authorization"
+// metadata in same "Bearer <token>" or "ApiKey <key>" format
+func (app *app) grpcAuthenticate(ctx context.Context, method string) (context.Context, error) {
+	user := users.AnonUser
+
+	var (
+		claims *signedtokens.Claims
+		key    *acttokens.APIKey
+	)
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get("authorization"); len(values) > 0 {
+		headerParts := strings.Split(values[0], " ")
+
+		if len(headerParts) != 2 {
+			return nil, status.Error(codes.Unauthenticated, app.grpcT(ctx, "error.invalid_token", nil))
+		}
+
+		var err error
+
+		user, claims, key, err = app.authenticateCredentials(headerParts[0], headerParts[1])
+
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrRecordNotFound):
+				return nil, status.Error(codes.Unauthenticated, app.grpcT(ctx, "error.invalid_token", nil))
+			default:
+				return nil, app.grpcServerError(ctx, err)
+			}
+		}
+	}
+
+	if code, ok := grpcPermissions[method]; ok {
+		if user.IsAnon() {
+			return nil, status.Error(codes.Unauthenticated, app.grpcT(ctx, "error.authentication_required", nil))
+		}
+
+		if !user.Activated {
+			return nil, status.Error(codes.PermissionDenied, app.grpcT(ctx, "error.inactive_account", nil))
+		}
+
+		permissions, err := app.tokenPermissions(user, claims, key)
+		if err != nil {
+			return nil, app.grpcServerError(ctx, err)
+		}
+
+		if !permissions.IsInclude(code) {
+			return nil, status.Error(codes.PermissionDenied, app.grpcT(ctx, "error.not_permitted", nil))
+		}
+	}
+
+	return appcontext.WithUser(ctx, user), nil
+}
+
+// grpcLocale picks locale from "accept-language" metadata
+func (app *app) grpcLocale(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	return app.i18n.Match(strings.Join(md.Get("accept-language"), ","))
+}
+
+func (app *app) grpcT(ctx context.Context, key string, params map[string]any) string {
+	return app.i18n.T(app.grpcLocale(ctx), key, params)
+}
+
+// grpcServerError logs err and hides its details from client
+func (app *app) grpcServerError(ctx context.Context, err error) error {
+	method, _ := grpc.Method(ctx)
+
+	app.logger.PrintError(err, map[string]string{
+		"grpc_method": method,
+	})
+
+	return status.Error(codes.Internal, app.grpcT(ctx, "error.server_error", nil))
+}
+
+func (app *app) grpcNotFound(ctx context.Context) error {
+	return status.Error(codes.NotFound, app.grpcT(ctx, "error.not_found", nil))
+}
+
+func (app *app) grpcEditConflict(ctx context.Context) error {
+	return status.Error(codes.Aborted, app.grpcT(ctx, "error.edit_conflict", nil))
+}
+
+func (app *app) grpcPreconditionFailed(ctx context.Context) error {
+	return status.Error(codes.FailedPrecondition, app.grpcT(ctx, "error.precondition_failed", nil))
+}
+
+// grpcValidationError reports every failed rule as
+// field violation of BadRequest error details
+func (app *app) grpcValidationError(ctx context.Context, errs validator.Errors) error {
+	errs = app.i18n.Errors(app.grpcLocale(ctx), errs)
+
+	fields := make([]string, 0, len(errs))
+
+	for field := range errs {
+		fields = append(fields, field)
+	}
+
+	sort.Strings(fields)
+
+	badRequest := &errdetails.BadRequest{}
+
+	for _, field := range fields {
+		for _, e := range errs[field] {
+			badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+				Field:       field,
+				Description: e.Message,
+			})
+		}
+	}
+
+	st := status.New(codes.InvalidArgument, app.grpcT(ctx, "error.failed_validation", nil))
+
+	withDetails, err := st.WithDetails(badRequest)
+	if err != nil {
+		return st.Err()
+	}
+
+	return withDetails.Err()
+}
@@ -0,0 +1,378 @@
+package main
+
+import (
+	"context"
+	"errors"
+	appcontext "movies-api/internal/context"
+	"movies-api/internal/models"
+	"movies-api/internal/models/acttokens"
+	"movies-api/internal/models/mfa"
+	"movies-api/internal/models/movies"
+	"movies-api/internal/models/users"
+	"movies-api/internal/pb"
+	"movies-api/internal/validator"
+	"net"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type moviesServer struct {
+	pb.UnimplementedMoviesServiceServer
+	app *app
+}
+
+func (s *moviesServer) Get(ctx context.Context, req *pb.GetMovieRequest) (*pb.Movie, error) {
+	movie, err := s.app.movieService.Get(req.Id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			return nil, s.app.grpcNotFound(ctx)
+		default:
+			return nil, s.app.grpcServerError(ctx, err)
+		}
+	}
+
+	return grpcMovie(movie), nil
+}
+
+// List streams movies same way as export does, so
+// result is not paginated
+func (s *moviesServer) List(req *pb.ListMoviesRequest, stream pb.MoviesService_ListServer) error {
+	ctx := stream.Context()
+
+	filters := movies.MovieFilters{
+		Title:        req.Title,
+		SearchMode:   req.SearchMode,
+		Genres:       req.Genres,
+		GenresMode:   req.GenresMode,
+		YearFrom:     int(req.YearFrom),
+		YearTo:       int(req.YearTo),
+		RuntimeMin:   int(req.RuntimeMin),
+		RuntimeMax:   int(req.RuntimeMax),
+		PersonId:     req.Person,
+		Sort:         req.Sort,
+		SortSafelist: []string{"id", "title", "year", "runtime", "rating", "-id", "-title", "-year", "-runtime", "-rating"},
+	}
+
+	if filters.SearchMode == "" {
+		filters.SearchMode = movies.SearchExact
+	}
+
+	if filters.GenresMode == "" {
+		filters.GenresMode = "all"
+	}
+
+	if filters.Sort == "" {
+		filters.Sort = "id"
+	}
+
+	v := validator.New()
+
+	if movies.ValidateFilterCriteria(v, filters); !v.Valid() {
+		return s.app.grpcValidationError(ctx, v.Errors)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.app.config.export.timeout)
+	defer cancel()
+
+	err := s.app.movieService.Export(ctx, &filters, func(m *movies.Movie) error {
+		return stream.Send(grpcMovie(m))
+	})
+
+	if err != nil {
+		// client went away or stream deadline exceeded
+		if ctx.Err() != nil {
+			return status.FromContextError(ctx.Err()).Err()
+		}
+
+		if _, ok := status.FromError(err); ok {
+			return err
+		}
+
+		return s.app.grpcServerError(ctx, err)
+	}
+
+	return nil
+}
+
+func (s *moviesServer) Create(ctx context.Context, req *pb.CreateMovieRequest) (*pb.Movie, error) {
+	movie := &movies.Movie{
+		Title:   req.Title,
+		Year:    req.Year,
+		Runtime: req.Runtime,
+		Genres:  req.Genres,
+	}
+
+	v := validator.New()
+
+	if movies.ValidateMovie(v, movie); !v.Valid() {
+		return nil, s.app.grpcValidationError(ctx, v.Errors)
+	}
+
+	err := s.app.movieService.Create(movie, appcontext.UserFrom(ctx).Id)
+
+	if err != nil {
+		return nil, s.app.grpcServerError(ctx, err)
+	}
+
+	return grpcMovie(movie), nil
+}
+
+func (s *moviesServer) Update(ctx context.Context, req *pb.UpdateMovieRequest) (*pb.Movie, error) {
+	movie, err := s.app.movieService.Get(req.Id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			return nil, s.app.grpcNotFound(ctx)
+		default:
+			return nil, s.app.grpcServerError(ctx, err)
+		}
+	}
+
+	// client can only update the version it has seen
+	if req.Version != 0 && req.Version != movie.Version {
+		return nil, s.app.grpcPreconditionFailed(ctx)
+	}
+
+	// unset fields are not changed
+	if req.Title != nil {
+		movie.Title = *req.Title
+	}
+
+	if req.Year != nil {
+		movie.Year = *req.Year
+	}
+
+	if req.Runtime != nil {
+		movie.Runtime = *req.Runtime
+	}
+
+	if req.Genres != nil {
+		movie.Genres = req.Genres.Values
+	}
+
+	v := validator.New()
+
+	if movies.ValidateMovie(v, movie); !v.Valid() {
+		return nil, s.app.grpcValidationError(ctx, v.Errors)
+	}
+
+	err = s.app.movieService.Update(movie, appcontext.UserFrom(ctx).Id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrEditConflict) && req.Version != 0:
+			return nil, s.app.grpcPreconditionFailed(ctx)
+		case errors.Is(err, models.ErrEditConflict):
+			return nil, s.app.grpcEditConflict(ctx)
+		default:
+			return nil, s.app.grpcServerError(ctx, err)
+		}
+	}
+
+	return grpcMovie(movie), nil
+}
+
+func (s *moviesServer) Delete(ctx context.Context, req *pb.DeleteMovieRequest) (*pb.DeleteMovieResponse, error) {
+	err := s.app.movieService.Delete(req.Id, req.Version)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound) && req.Version != 0:
+			return nil, s.app.grpcPreconditionFailed(ctx)
+		case errors.Is(err, models.ErrRecordNotFound):
+			return nil, s.app.grpcNotFound(ctx)
+		default:
+			return nil, s.app.grpcServerError(ctx, err)
+		}
+	}
+
+	return &pb.DeleteMovieResponse{}, nil
+}
+
+func grpcMovie(m *movies.Movie) *pb.Movie {
+	return &pb.Movie{
+		Id:      m.Id,
+		Title:   m.Title,
+		Year:    m.Year,
+		Runtime: m.Runtime,
+		Genres:  m.Genres,
+		Rating:  m.Rating,
+		Votes:   m.Votes,
+		Version: m.Version,
+	}
+}
+
+type authServer struct {
+	pb.UnimplementedAuthServiceServer
+	app *app
+}
+
+func (s *authServer) CreateToken(ctx context.Context, req *pb.CreateTokenRequest) (*pb.Token, error) {
+	v := validator.New()
+
+	invalidCredentials := status.Error(codes.Unauthenticated, s.app.grpcT(ctx, "error.invalid_credentials", nil))
+
+	if req.MfaToken != "" {
+		if mfa.ValidateSecondStep(v, req.MfaToken, req.Code); !v.Valid() {
+			return nil, s.app.grpcValidationError(ctx, v.Errors)
+		}
+
+		user, err := s.app.verifySecondFactor(req.MfaToken, req.Code)
+
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrRecordNotFound):
+				return nil, status.Error(codes.Unauthenticated, s.app.grpcT(ctx, "error.invalid_token", nil))
+			case errors.Is(err, mfa.ErrInvalidCode):
+				return nil, invalidCredentials
+			default:
+				return nil, s.app.grpcServerError(ctx, err)
+			}
+		}
+
+		return s.newSession(ctx, user)
+	}
+
+	users.ValidateEmail(v, req.Email)
+	users.ValidatePasswordPlaintext(v, req.Password)
+
+	if !v.Valid() {
+		return nil, s.app.grpcValidationError(ctx, v.Errors)
+	}
+
+	user, err := s.app.userService.GetByEmail(req.Email)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			return nil, invalidCredentials
+		default:
+			return nil, s.app.grpcServerError(ctx, err)
+		}
+	}
+
+	isMatch, err := user.Password.Matches(req.Password)
+
+	if err != nil {
+		return nil, s.app.grpcServerError(ctx, err)
+	}
+
+	if !isMatch {
+		return nil, invalidCredentials
+	}
+
+	if user.MFAEnabled {
+		token, err := s.app.actTokenService.New(user.Id, mfaPendingTTL, acttokens.ScopeMFAPending)
+
+		if err != nil {
+			return nil, s.app.grpcServerError(ctx, err)
+		}
+
+		return &pb.Token{MfaToken: token.Plaintext, MfaExpiry: timestamppb.New(token.Expiry)}, nil
+	}
+
+	return s.newSession(ctx, user)
+}
+
+// newSession starts session of user and returns its tokens
+func (s *authServer) newSession(ctx context.Context, user *users.User) (*pb.Token, error) {
+	userAgent, ip := grpcClient(ctx)
+
+	token, refreshToken, err := s.app.actTokenService.NewSession(user.Id, s.app.config.auth.accessTTL, s.app.config.auth.refreshTTL, userAgent, ip)
+
+	if err != nil {
+		return nil, s.app.grpcServerError(ctx, err)
+	}
+
+	err = s.app.signToken(token)
+
+	if err != nil {
+		return nil, s.app.grpcServerError(ctx, err)
+	}
+
+	return grpcToken(token, refreshToken), nil
+}
+
+func (s *authServer) RefreshToken(ctx context.Context, req *pb.RefreshTokenRequest) (*pb.Token, error) {
+	v := validator.New()
+
+	if acttokens.ValidateTokenPlaintext(v, req.RefreshToken); !v.Valid() {
+		return nil, s.app.grpcValidationError(ctx, v.Errors)
+	}
+
+	userAgent, ip := grpcClient(ctx)
+
+	token, refreshToken, err := s.app.actTokenService.Refresh(req.RefreshToken, s.app.config.auth.accessTTL, s.app.config.auth.refreshTTL, userAgent, ip)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, acttokens.ErrTokenReused):
+			s.app.logger.PrintInfo("refresh token reused, session revoked", map[string]string{
+				"ip": ip,
+			})
+
+			if err := s.app.reloadRevocations(); err != nil {
+				s.app.logger.PrintError(err, nil)
+			}
+
+			return nil, status.Error(codes.Unauthenticated, s.app.grpcT(ctx, "error.invalid_token", nil))
+		case errors.Is(err, models.ErrRecordNotFound):
+			return nil, status.Error(codes.Unauthenticated, s.app.grpcT(ctx, "error.invalid_token", nil))
+		default:
+			return nil, s.app.grpcServerError(ctx, err)
+		}
+	}
+
+	err = s.app.signToken(token)
+
+	if err != nil {
+		return nil, s.app.grpcServerError(ctx, err)
+	}
+
+	return grpcToken(token, refreshToken), nil
+}
+
+func grpcToken(token, refreshToken *acttokens.ActToken) *pb.Token {
+	return &pb.Token{
+		Token:         token.Plaintext,
+		Expiry:        timestamppb.New(token.Expiry),
+		RefreshToken:  refreshToken.Plaintext,
+		RefreshExpiry: timestamppb.New(refreshToken.Expiry),
+	}
+}
+
+// grpcClient returns user agent and ip of client for its session
+func grpcClient(ctx context.Context) (string, string) {
+	var userAgent string
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if values := md.Get("user-agent"); len(values) > 0 {
+		userAgent = values[0]
+	}
+
+	return userAgent, grpcPeerIP(ctx)
+}
+
+// grpcPeerIP returns ip of client without port
+func grpcPeerIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	ip := p.Addr.String()
+
+	if host, _, err := net.SplitHostPort(ip); err == nil {
+		ip = host
+	}
+
+	return ip
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"movies-api/internal/context"
+	"movies-api/internal/models/movies"
+	"movies-api/internal/utils"
+	"movies-api/internal/validator"
+	"net/http"
+	"strconv"
+)
+
+const (
+	contentTypeCSV    = "text/csv"
+	contentTypeNDJSON = "application/x-ndjson"
+)
+
+func (app *app) importMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	dryRun := utils.ReadBool(r.URL.Query(), "dry_run", false, v)
+
+	if !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	maxBytes := 10 * 1048576 // 10 MB
+	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+
+	var rows []*movies.ImportRow
+	var err error
+
+	switch mediaType {
+	case contentTypeCSV:
+		rows, err = movies.ParseCSV(r.Body)
+	case contentTypeNDJSON:
+		rows, err = movies.ParseNDJSON(r.Body)
+	default:
+		app.err.unsupportedMediaTypeResponse(w, r, contentTypeCSV, contentTypeNDJSON)
+		return
+	}
+
+	if err != nil {
+		var maxBytesError *http.MaxBytesError
+
+		switch {
+		case errors.As(err, &maxBytesError):
+			app.err.badRequestResponse(w, r, utils.NewBadRequestError(utils.CodeBodyTooLarge, map[string]any{"bytes": maxBytesError.Limit}, fmt.Sprintf("body must be less than %d bytes", maxBytesError.Limit)))
+		case errors.Is(err, movies.ErrEmptyBody):
+			app.err.badRequestResponse(w, r, utils.NewBadRequestError(utils.CodeEmptyBody, nil, err.Error()))
+		case errors.Is(err, movies.ErrMissingColumns):
+			app.err.badRequestResponse(w, r, utils.NewBadRequestError(utils.CodeMissingColumns, map[string]any{"columns": movies.ImportColumns}, err.Error()))
+		default:
+			app.err.badRequestResponse(w, r, err)
+		}
+		return
+	}
+
+	// errors are keyed by line number of the row in file
+	rowErrors := make(map[string]validator.Errors)
+	valid := []*movies.Movie{}
+
+	for _, row := range rows {
+		// parsing errors go first, so they are reported
+		// together with failed rules of the same row
+		rv := &validator.Validator{Errors: row.Errors}
+
+		movies.ValidateMovie(rv, row.Movie)
+
+		if !rv.Valid() {
+			rowErrors[strconv.Itoa(row.Line)] = app.i18n.Errors(context.ContextGetLocale(r), rv.Errors)
+			continue
+		}
+
+		valid = append(valid, row.Movie)
+	}
+
+	if !dryRun && len(valid) > 0 {
+		err = app.movieService.Import(valid, context.ContextGetUser(r).Id)
+
+		if err != nil {
+			app.err.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	imported := len(valid)
+	if dryRun {
+		imported = 0
+	}
+
+	env := utils.Envelope{
+		"dry_run":  dryRun,
+		"total":    len(rows),
+		"valid":    len(valid),
+		"imported": imported,
+		"errors":   rowErrors,
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, env, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
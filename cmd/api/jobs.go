@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// purgeTrash periodically removes movies which are in trash
+// longer than retention period. It stops when done is closed
+func (app *app) purgeTrash(done <-chan struct{}) {
+	// non positive interval disables purging
+	if app.config.trash.purgeInterval <= 0 {
+		return
+	}
+
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		// recover to catch any panics
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.PrintError(fmt.Errorf("%s", err), nil)
+			}
+		}()
+
+		ticker := time.NewTicker(app.config.trash.purgeInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				purged, err := app.movieService.PurgeDeleted(app.config.trash.retention)
+				if err != nil {
+					app.logger.PrintError(err, nil)
+					continue
+				}
+
+				if purged > 0 {
+					app.logger.PrintInfo("purged movies from trash", map[string]string{
+						"count": strconv.FormatInt(purged, 10),
+					})
+				}
+			}
+		}
+	}()
+}
+
+// syncRevocations periodically reloads revoked signed tokens,
+// so tokens revoked by other instances are rejected too.
+// Revoked tokens which expired anyway are purged
+func (app *app) syncRevocations(done <-chan struct{}) {
+	if app.signer == nil || app.config.auth.revocationSync <= 0 {
+		return
+	}
+
+	app.wg.Add(1)
+
+	go func() {
+		defer app.wg.Done()
+
+		// recover to catch any panics
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.PrintError(fmt.Errorf("%s", err), nil)
+			}
+		}()
+
+		ticker := time.NewTicker(app.config.auth.revocationSync)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := app.actTokenService.PurgeRevoked()
+				if err != nil {
+					app.logger.PrintError(err, nil)
+				}
+
+				err = app.reloadRevocations()
+				if err != nil {
+					app.logger.PrintError(err, nil)
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"errors"
+	"movies-api/internal/models"
+	"movies-api/internal/models/acttokens"
+	"movies-api/internal/models/mfa"
+	"movies-api/internal/models/permissions"
+	"movies-api/internal/models/users"
+	"movies-api/internal/totp"
+	"movies-api/internal/utils"
+	"movies-api/internal/validator"
+	"net/http"
+	"time"
+)
+
+const (
+	mfaIssuer = "Movies API"
+	// mfaPendingTTL is time user has to enter code after password
+	mfaPendingTTL = 5 * time.Minute
+)
+
+// verifySecondFactor uses mfa token issued after password was checked
+// and code of its owner. Token can be used only once, so each
+// guess of code requires password again
+func (app *app) verifySecondFactor(mfaToken, code string) (*users.User, error) {
+	userID, err := app.actTokenService.Consume(acttokens.ScopeMFAPending, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := app.userService.Get(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.mfaService.Verify(user.Id, code)
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// mfaPermissions removes permissions which are only granted
+// with MFA from permissions of user who has not enabled it
+func (app *app) mfaPermissions(user *users.User, perms permissions.Permissions) permissions.Permissions {
+	if user.MFAEnabled || len(app.config.auth.mfaPermissions) == 0 {
+		return perms
+	}
+
+	granted := permissions.Permissions{}
+
+	for _, code := range perms {
+		if !validator.AllowedValues(code, app.config.auth.mfaPermissions...) {
+			granted = append(granted, code)
+		}
+	}
+
+	return granted
+}
+
+// grantedPermissions returns permissions user can use right now
+func (app *app) grantedPermissions(user *users.User) (permissions.Permissions, error) {
+	perms, err := app.permissionsService.GetAllForUser(user.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	return app.mfaPermissions(user, perms), nil
+}
+
+// enrollMFAHandler starts TOTP enrollment, it is finished
+// when user confirms code from authenticator app
+func (app *app) enrollMFAHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := app.fullUser(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	secret, err := app.mfaService.Enroll(user.Id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, mfa.ErrAlreadyEnabled):
+			v := validator.New()
+			v.AddCode("mfa", validator.CodeMFAEnabled, nil, "two-factor authentication is already enabled")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	enrollment := mfa.Enrollment{
+		Secret:          totp.EncodeSecret(secret),
+		ProvisioningURI: totp.ProvisioningURI(secret, mfaIssuer, user.Email),
+	}
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"mfa": enrollment}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+// confirmMFAHandler enables MFA and responds with recovery codes,
+// it is not possible to get them later
+func (app *app) confirmMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if mfa.ValidateCode(v, input.Code); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.fullUser(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	codes, err := app.mfaService.Confirm(user.Id, input.Code)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			v.AddCode("code", validator.CodeMFANotStarted, nil, "two-factor authentication enrollment was not started")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, mfa.ErrInvalidCode):
+			v.AddCode("code", validator.CodeInvalidMFACode, nil, "invalid code")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"recovery_codes": codes}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+// disableMFAHandler requires valid code, so stolen
+// session can not turn MFA off
+func (app *app) disableMFAHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if mfa.ValidateCode(v, input.Code); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user, err := app.fullUser(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.mfaService.Verify(user.Id, input.Code)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, mfa.ErrInvalidCode):
+			v.AddCode("code", validator.CodeInvalidMFACode, nil, "invalid code")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.mfaService.Disable(user.Id)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "two-factor authentication successfully disabled"}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
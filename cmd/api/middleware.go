@@ -8,7 +8,7 @@ import (
 	"movies-api/internal/models"
 	"movies-api/internal/models/acttokens"
 	"movies-api/internal/models/users"
-	"movies-api/internal/validator"
+	"movies-api/internal/signedtokens"
 	"net/http"
 	"strconv"
 	"strings"
@@ -16,6 +16,7 @@ import (
 	"time"
 
 	"github.com/felixge/httpsnoop"
+	"github.com/go-chi/chi/v5"
 	"github.com/tomasen/realip"
 	"golang.org/x/time/rate"
 )
@@ -24,6 +25,11 @@ func (app *app) recoverPanic(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if err := recover(); err != nil {
+				// handler intentionally aborted response
+				if err == http.ErrAbortHandler {
+					panic(err)
+				}
+
 				w.Header().Set("Connection", "close")
 				app.err.serverErrorResponse(w, r, fmt.Errorf("%s", err))
 			}
@@ -33,16 +39,26 @@ func (app *app) recoverPanic(next http.Handler) http.Handler {
 	})
 }
 
-func (app *app) rateLimit(next http.Handler) http.Handler {
-	type client struct {
-		limiter  *rate.Limiter
-		lastSeen time.Time
-	}
+// clientLimiter keeps rate limiter of each client, so REST
+// and gRPC servers limit clients the same way
+type clientLimiter struct {
+	rps     float64
+	burst   int
+	mu      sync.Mutex
+	clients map[string]*client
+}
 
-	var (
-		mu      sync.Mutex
-		clients = make(map[string]*client)
-	)
+type client struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func newClientLimiter(rps float64, burst int) *clientLimiter {
+	l := &clientLimiter{
+		rps:     rps,
+		burst:   burst,
+		clients: make(map[string]*client),
+	}
 
 	// goroutine to clear client if they
 	// havent been seen in last 3 minutes
@@ -50,41 +66,82 @@ func (app *app) rateLimit(next http.Handler) http.Handler {
 		for {
 			time.Sleep(time.Minute)
 
-			mu.Lock()
+			l.mu.Lock()
 
-			for ip, client := range clients {
+			for ip, client := range l.clients {
 				if time.Since(client.lastSeen) > 3*time.Minute {
-					delete(clients, ip)
+					delete(l.clients, ip)
 				}
 			}
 
-			mu.Unlock()
+			l.mu.Unlock()
 		}
 	}()
 
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		ip := realip.FromRequest(r)
+	return l
+}
 
-		mu.Lock()
+// Allow reports whether client with ip may make request now
+func (l *clientLimiter) Allow(ip string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 
-		if _, found := clients[ip]; !found {
-			clients[ip] = &client{
-				limiter: rate.NewLimiter(rate.Limit(app.config.limiter.rps), app.config.limiter.burst),
-			}
+	if _, found := l.clients[ip]; !found {
+		l.clients[ip] = &client{
+			limiter: rate.NewLimiter(rate.Limit(l.rps), l.burst),
 		}
+	}
 
-		clients[ip].lastSeen = time.Now()
+	l.clients[ip].lastSeen = time.Now()
 
-		if !clients[ip].limiter.Allow() {
-			mu.Unlock()
-			app.err.rateLimitExceededResponse(w, r)
-			return
-		}
+	return l.clients[ip].limiter.Allow()
+}
 
-		mu.Unlock()
+// rateLimit creates per client rate limiter middleware.
+// Each call creates separate budget of requests
+func (app *app) rateLimit(rps float64, burst int) func(http.Handler) http.Handler {
+	limiter := newClientLimiter(rps, burst)
 
-		next.ServeHTTP(w, r)
-	})
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !app.config.limiter.enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !limiter.Allow(realip.FromRequest(r)) {
+				app.err.rateLimitExceededResponse(w, r)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// skipRoutes applies middleware to every request except ones
+// routed by mux to one of patterns. Matching on route pattern
+// instead of raw path keeps variants like trailing slash,
+// which are routed elsewhere, under the middleware
+func (app *app) skipRoutes(mux chi.Routes, middleware func(http.Handler) http.Handler, patterns ...string) func(http.Handler) http.Handler {
+	skip := make(map[string]bool, len(patterns))
+	for _, pattern := range patterns {
+		skip[pattern] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		wrapped := middleware(next)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rctx := chi.NewRouteContext()
+			if mux.Match(rctx, r.Method, r.URL.Path) && skip[rctx.RoutePattern()] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			wrapped.ServeHTTP(w, r)
+		})
+	}
 }
 
 func (app *app) authenticate(next http.Handler) http.Handler {
@@ -106,22 +163,15 @@ func (app *app) authenticate(next http.Handler) http.Handler {
 		// split auth header in 2 parts
 		headerParts := strings.Split(authHeader, " ")
 
-		if len(headerParts) != 2 || headerParts[0] != "Bearer" {
+		if len(headerParts) != 2 {
 			app.err.invalidAuthenticationTokenResponse(w, r)
 			return
 		}
 
-		token := headerParts[1]
-
-		v := validator.New()
-
-		if acttokens.ValidateTokenPlaintext(v, token); !v.Valid() {
-			app.err.invalidAuthenticationTokenResponse(w, r)
-			return
-		}
+		credential := headerParts[1]
 
-		// find user by his token
-		user, err := app.userService.GetByToken(acttokens.ScopeAuth, token)
+		// find user by his token or api key
+		user, claims, key, err := app.authenticateCredentials(headerParts[0], credential)
 
 		if err != nil {
 			switch {
@@ -133,12 +183,39 @@ func (app *app) authenticate(next http.Handler) http.Handler {
 			return
 		}
 
-		// set user in context
+		// set user and token in context
 		r = context.ContextSetUser(r, user)
+
+		if key != nil {
+			r = context.ContextSetAPIKey(r, key)
+		} else {
+			r = context.ContextSetToken(r, credential)
+		}
+
+		if claims != nil {
+			r = context.ContextSetClaims(r, claims)
+		}
+
 		next.ServeHTTP(w, r)
 	})
 }
 
+// authenticateCredentials checks credential of "Bearer" or "ApiKey"
+// authorization scheme. Claims are returned for signed tokens
+// and key for api keys
+func (app *app) authenticateCredentials(scheme, credential string) (*users.User, *signedtokens.Claims, *acttokens.APIKey, error) {
+	switch scheme {
+	case "Bearer":
+		user, claims, err := app.authenticateToken(credential)
+		return user, claims, nil, err
+	case "ApiKey":
+		user, key, err := app.authenticateAPIKey(credential)
+		return user, nil, key, err
+	default:
+		return nil, nil, nil, models.ErrRecordNotFound
+	}
+}
+
 func (app *app) requireAuthenticatedUser(next http.Handler) http.HandlerFunc {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := context.ContextGetUser(r)
@@ -152,6 +229,21 @@ func (app *app) requireAuthenticatedUser(next http.Handler) http.HandlerFunc {
 	})
 }
 
+// requireSessionUser guards account management, so
+// api keys can not be used to create keys or change account
+func (app *app) requireSessionUser(next http.Handler) http.HandlerFunc {
+	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if context.ContextGetAPIKey(r) != nil {
+			app.err.notPermittedResponse(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+
+	return app.requireAuthenticatedUser(fn)
+}
+
 func (app *app) requireActivatedUser(next http.Handler) http.HandlerFunc {
 	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		user := context.ContextGetUser(r)
@@ -169,10 +261,8 @@ func (app *app) requireActivatedUser(next http.Handler) http.HandlerFunc {
 
 func (app *app) requirePermission(code string, next http.HandlerFunc) http.HandlerFunc {
 	fn := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		user := context.ContextGetUser(r)
-
 		// get user permissions
-		permissions, err := app.permissionsService.GetAllForUser(user.Id)
+		permissions, err := app.userPermissions(r)
 		if err != nil {
 			app.err.serverErrorResponse(w, r, err)
 			return
@@ -202,11 +292,12 @@ func (app *app) enableCORS(next http.Handler) http.Handler {
 
 				if origin == app.config.cors.trustedOrigins[i] {
 					w.Header().Set("Access-Control-Allow-Origin", "*")
+					w.Header().Set("Access-Control-Expose-Headers", "ETag")
 
 					// check if request is preflight request
 					if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
 						w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+						w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, If-Match, If-None-Match")
 						w.WriteHeader(http.StatusOK)
 						return
 					}
@@ -220,6 +311,20 @@ func (app *app) enableCORS(next http.Handler) http.Handler {
 	})
 }
 
+// localize picks locale of messages from Accept-Language header
+func (app *app) localize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Language")
+
+		locale := app.i18n.Match(r.Header.Get("Accept-Language"))
+
+		w.Header().Set("Content-Language", locale)
+
+		r = context.ContextSetLocale(r, locale)
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (app *app) metrics(next http.Handler) http.Handler {
 	totalReqReceived := expvar.NewInt("total_requests_received")
 	totalResSent := expvar.NewInt("total_responses_sent")
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"movies-api/internal/i18n"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestSkipRoutesRateLimit checks that suggestions are limited only
+// by own budget and do not eat default one
+func TestSkipRoutesRateLimit(t *testing.T) {
+	cat, err := i18n.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := &app{i18n: cat, err: CustomError{i18n: cat}}
+	app.config.limiter.enabled = true
+
+	ok := func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	r := chi.NewRouter()
+	r.Use(app.skipRoutes(r, app.rateLimit(0, 1), suggestPattern))
+
+	r.Route("/v1", func(r chi.Router) {
+		r.With(app.rateLimit(0, 3)).Get("/movies/suggest", ok)
+
+		r.Mount("/movies", func() http.Handler {
+			r := chi.NewRouter()
+			r.Get("/", ok)
+			return r
+		}())
+	})
+
+	tests := []struct {
+		path string
+		want int
+	}{
+		{"/v1/movies/suggest", http.StatusOK},
+		{"/v1/movies/suggest", http.StatusOK},
+		{"/v1/movies/suggest", http.StatusOK},
+		// own budget is spent
+		{"/v1/movies/suggest", http.StatusTooManyRequests},
+		// default budget is untouched
+		{"/v1/movies", http.StatusOK},
+		{"/v1/movies", http.StatusTooManyRequests},
+		// not routed to suggestions, so limited by default budget
+		{"/v1/movies/suggest/", http.StatusTooManyRequests},
+	}
+
+	for i, tt := range tests {
+		rr := httptest.NewRecorder()
+		r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+		if rr.Code != tt.want {
+			t.Errorf("#%d %s: want %d, got %d", i, tt.path, tt.want, rr.Code)
+		}
+	}
+}
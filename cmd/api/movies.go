@@ -3,11 +3,13 @@ package main
 import (
 	"errors"
 	"fmt"
+	"movies-api/internal/context"
 	"movies-api/internal/models"
 	"movies-api/internal/models/movies"
 	"movies-api/internal/utils"
 	"movies-api/internal/validator"
 	"net/http"
+	"strings"
 )
 
 func (app *app) showMovieHandler(w http.ResponseWriter, r *http.Request) {
@@ -30,7 +32,24 @@ func (app *app) showMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"movie": movie}, nil)
+	etag, err := app.movieETag(movie)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// client already has current version of the movie
+	if inm := r.Header.Get("If-None-Match"); inm != "" && utils.MatchETag(inm, etag, true) {
+		w.Header().Set("ETag", etag)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag)
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"movie": movie}, headers)
 
 	if err != nil {
 		app.err.serverErrorResponse(w, r, err)
@@ -66,7 +85,7 @@ func (app *app) createMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.movieService.Create(movie)
+	err = app.movieService.Create(movie, context.ContextGetUser(r).Id)
 
 	if err != nil {
 		app.err.serverErrorResponse(w, r, err)
@@ -105,6 +124,21 @@ func (app *app) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	etag, err := app.movieETag(movie)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	// client can only update the version it has seen
+	ifMatch := r.Header.Get("If-Match")
+
+	if ifMatch != "" && !utils.MatchETag(ifMatch, etag, false) {
+		app.err.preconditionFailedResponse(w, r)
+		return
+	}
+
 	var input struct {
 		Title   *string  `json:"title"`
 		Year    *int32   `json:"year"`
@@ -145,10 +179,12 @@ func (app *app) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.movieService.Update(movie)
+	err = app.movieService.Update(movie, context.ContextGetUser(r).Id)
 
 	if err != nil {
 		switch {
+		case errors.Is(err, models.ErrEditConflict) && ifMatch != "":
+			app.err.preconditionFailedResponse(w, r)
 		case errors.Is(err, models.ErrEditConflict):
 			app.err.editConflictResponse(w, r)
 		default:
@@ -157,7 +193,17 @@ func (app *app) updateMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"movie": movie}, nil)
+	etag, err = utils.ContentETag(movie.Version, movie)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("ETag", etag)
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"movie": movie}, headers)
 
 	if err != nil {
 		app.err.serverErrorResponse(w, r, err)
@@ -172,10 +218,45 @@ func (app *app) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = app.movieService.Delete(id)
+	var version int32
+
+	// with If-Match movie is deleted only if client has seen its current version
+	ifMatch := r.Header.Get("If-Match")
+
+	if ifMatch != "" {
+		movie, err := app.movieService.Get(id)
+
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrRecordNotFound):
+				app.err.notFoundResponse(w, r)
+			default:
+				app.err.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		etag, err := app.movieETag(movie)
+
+		if err != nil {
+			app.err.serverErrorResponse(w, r, err)
+			return
+		}
+
+		if !utils.MatchETag(ifMatch, etag, false) {
+			app.err.preconditionFailedResponse(w, r)
+			return
+		}
+
+		version = movie.Version
+	}
+
+	err = app.movieService.Delete(id, version)
 
 	if err != nil {
 		switch {
+		case errors.Is(err, models.ErrRecordNotFound) && ifMatch != "":
+			app.err.preconditionFailedResponse(w, r)
 		case errors.Is(err, models.ErrRecordNotFound):
 			app.err.notFoundResponse(w, r)
 		default:
@@ -184,7 +265,7 @@ func (app *app) deleteMovieHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"movie": "movie successfuly deleted"}, nil)
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"movie": "movie successfuly moved to trash"}, nil)
 
 	if err != nil {
 		app.err.serverErrorResponse(w, r, err)
@@ -200,27 +281,92 @@ func (app *app) listMoviesHandler(w http.ResponseWriter, r *http.Request) {
 	v := validator.New()
 
 	input.Title = utils.ReadQuery(r.URL.Query(), "title", "")
+	input.SearchMode = utils.ReadQuery(r.URL.Query(), "search_mode", movies.SearchExact)
 	input.Genres = utils.ReadCSV(r.URL.Query(), "genres", []string{})
+	input.GenresMode = utils.ReadQuery(r.URL.Query(), "genres_mode", "all")
+	input.YearFrom = utils.ReadInt(r.URL.Query(), "year_from", 0, v)
+	input.YearTo = utils.ReadInt(r.URL.Query(), "year_to", 0, v)
+	input.RuntimeMin = utils.ReadInt(r.URL.Query(), "runtime_min", 0, v)
+	input.RuntimeMax = utils.ReadInt(r.URL.Query(), "runtime_max", 0, v)
+	input.PersonId = int64(utils.ReadInt(r.URL.Query(), "person", 0, v))
+	input.UserId = context.ContextGetUser(r).Id
 	input.Page = utils.ReadInt(r.URL.Query(), "page", 1, v)
 	input.PageSize = utils.ReadInt(r.URL.Query(), "page_size", 10, v)
+	input.Cursor = utils.ReadQuery(r.URL.Query(), "cursor", "")
+	// total is skipped by default in cursor mode
+	input.WithTotal = utils.ReadBool(r.URL.Query(), "with_total", input.Cursor == "", v)
 	input.Sort = utils.ReadQuery(r.URL.Query(), "sort", "id")
-	input.SortSafelist = []string{"id", "title", "year", "runtime", "-id", "-title", "-year", "-runtime"}
+	input.SortSafelist = []string{"id", "title", "year", "runtime", "rating", "-id", "-title", "-year", "-runtime", "-rating", "relevance"}
+
+	facets := utils.ReadCSV(r.URL.Query(), "facets", []string{})
+
+	movies.ValidateFacets(v, facets)
 
 	if movies.ValidateFilters(v, input.MovieFilters); !v.Valid() {
 		app.err.failedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	movies, meta, err := app.movieService.GetAll(&input.MovieFilters)
+	list, meta, err := app.movieService.GetAll(&input.MovieFilters)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	env := utils.Envelope{"movies": list, "metadata": meta}
+
+	if len(facets) > 0 {
+		env["facets"], err = app.movieService.GetFacets(&input.MovieFilters, facets)
+
+		if err != nil {
+			app.err.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, env, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) suggestMoviesHandler(w http.ResponseWriter, r *http.Request) {
+	v := validator.New()
+
+	prefix := strings.TrimSpace(utils.ReadQuery(r.URL.Query(), "q", ""))
+	limit := utils.ReadInt(r.URL.Query(), "limit", 10, v)
+
+	if movies.ValidateSuggest(v, prefix, limit); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions, err := app.movieService.Suggest(prefix, limit)
 
 	if err != nil {
 		app.err.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"movies": movies, "metadata": meta}, nil)
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"suggestions": suggestions}, nil)
 
 	if err != nil {
 		app.err.serverErrorResponse(w, r, err)
 	}
 }
+
+// movieETag loads credits of the movie and tags it the way show handler
+// returns it. Credits and rating change without version bump, so
+// tag built from version alone would keep stale copies in caches
+func (app *app) movieETag(movie *movies.Movie) (string, error) {
+	credits, err := app.peopleService.GetCreditsForMovie(movie.Id)
+	if err != nil {
+		return "", err
+	}
+
+	movie.Credits = credits
+
+	return utils.ContentETag(movie.Version, movie)
+}
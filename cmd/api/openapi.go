@@ -0,0 +1,22 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// openAPISpec describes every route of app.routes(),
+// openapi_test.go checks that they stay in sync
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+func (app *app) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	_, err := w.Write(openAPISpec)
+	if err != nil {
+		app.err.logError(r, err)
+	}
+}
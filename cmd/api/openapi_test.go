@@ -0,0 +1,64 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// TestOpenAPICoversRoutes fails when route is added without
+// spec entry or spec describes route which does not exist
+func TestOpenAPICoversRoutes(t *testing.T) {
+	var spec struct {
+		OpenAPI string                                `json:"openapi"`
+		Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	}
+
+	err := json.Unmarshal(openAPISpec, &spec)
+	if err != nil {
+		t.Fatalf("invalid spec: %s", err)
+	}
+
+	if !strings.HasPrefix(spec.OpenAPI, "3.1") {
+		t.Errorf("want openapi 3.1, got %q", spec.OpenAPI)
+	}
+
+	app := &app{}
+	routes := make(map[string]bool)
+
+	walkFn := func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		// mounted routers add trailing slash to their root route
+		if len(route) > 1 {
+			route = strings.TrimSuffix(route, "/")
+		}
+
+		method = strings.ToLower(method)
+		routes[method+" "+route] = true
+
+		if _, ok := spec.Paths[route][method]; !ok {
+			t.Errorf("route %s %s has no openapi entry", strings.ToUpper(method), route)
+		}
+
+		return nil
+	}
+
+	err = chi.Walk(app.routes().(chi.Routes), walkFn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for path, operations := range spec.Paths {
+		for method := range operations {
+			if method == "parameters" {
+				continue
+			}
+
+			if !routes[method+" "+path] {
+				t.Errorf("openapi entry %s %s has no route", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
@@ -0,0 +1,346 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"movies-api/internal/models"
+	"movies-api/internal/models/people"
+	"movies-api/internal/utils"
+	"movies-api/internal/validator"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func (app *app) showPersonHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	person, err := app.peopleService.Get(id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"person": person}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) createPersonHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Name      string `json:"name"`
+		BirthYear int32  `json:"birth_year"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	person := &people.Person{
+		Name:      input.Name,
+		BirthYear: input.BirthYear,
+	}
+
+	v := validator.New()
+
+	if people.ValidatePerson(v, person); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.peopleService.Create(person)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	headers := make(http.Header)
+	headers.Set("Location", fmt.Sprintf("/v1/people/%d", person.Id))
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"person": person}, headers)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) updatePersonHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	person, err := app.peopleService.Get(id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name      *string `json:"name"`
+		BirthYear *int32  `json:"birth_year"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Name != nil {
+		person.Name = *input.Name
+	}
+
+	if input.BirthYear != nil {
+		person.BirthYear = *input.BirthYear
+	}
+
+	v := validator.New()
+
+	if people.ValidatePerson(v, person); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.peopleService.Update(person)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrEditConflict):
+			app.err.editConflictResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"person": person}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) deletePersonHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.peopleService.Delete(id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"person": "person successfuly deleted"}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) listPeopleHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		people.PeopleFilters
+	}
+
+	v := validator.New()
+
+	input.Name = utils.ReadQuery(r.URL.Query(), "name", "")
+	input.Page = utils.ReadInt(r.URL.Query(), "page", 1, v)
+	input.PageSize = utils.ReadInt(r.URL.Query(), "page_size", 10, v)
+
+	if people.ValidateFilters(v, input.PeopleFilters); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	people, meta, err := app.peopleService.GetAll(&input.PeopleFilters)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"people": people, "metadata": meta}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) listPersonCreditsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	// make sure person exists so unknown ids get 404 instead of empty list
+	_, err = app.peopleService.Get(id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	credits, err := app.peopleService.GetCreditsForPerson(id)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"credits": credits}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) createPersonCreditHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	person, err := app.peopleService.Get(id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		MovieId      int64  `json:"movie_id"`
+		Role         string `json:"role"`
+		Character    string `json:"character"`
+		BillingOrder int32  `json:"billing_order"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	credit := &people.Credit{
+		MovieId:      input.MovieId,
+		PersonId:     person.Id,
+		Name:         person.Name,
+		Role:         input.Role,
+		Character:    input.Character,
+		BillingOrder: input.BillingOrder,
+	}
+
+	v := validator.New()
+
+	if people.ValidateCredit(v, credit); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.peopleService.AddCredit(credit)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, people.ErrDuplicateCredit):
+			v.AddCode("role", validator.CodeDuplicateRole, nil, "person already has this role in the movie")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, people.ErrInvalidMovie):
+			v.AddCode("movie_id", validator.CodeMovieNotFound, nil, "movie with this id does not exist")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"credit": credit}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) deletePersonCreditHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	movieID, err := utils.ReadInt64Param(r, "movie_id")
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.peopleService.DeleteCredit(id, movieID, chi.URLParam(r, "role"))
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"credit": "credit successfuly deleted"}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
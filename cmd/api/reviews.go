@@ -0,0 +1,230 @@
+package main
+
+import (
+	"errors"
+	"movies-api/internal/context"
+	"movies-api/internal/models"
+	"movies-api/internal/models/reviews"
+	"movies-api/internal/utils"
+	"movies-api/internal/validator"
+	"net/http"
+)
+
+func (app *app) listMovieReviewsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	var input struct {
+		reviews.ReviewFilters
+	}
+
+	v := validator.New()
+
+	input.Page = utils.ReadInt(r.URL.Query(), "page", 1, v)
+	input.PageSize = utils.ReadInt(r.URL.Query(), "page_size", 10, v)
+
+	if reviews.ValidateFilters(v, input.ReviewFilters); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	// make sure movie exists so unknown ids get 404 instead of empty list
+	_, err = app.movieService.Get(id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	reviews, meta, err := app.reviewService.GetAllForMovie(id, &input.ReviewFilters)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"reviews": reviews, "metadata": meta}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) createMovieReviewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.movieService.Get(id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Rating int32  `json:"rating"`
+		Body   string `json:"body"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	user := context.ContextGetUser(r)
+
+	review := &reviews.Review{
+		MovieId: movie.Id,
+		UserId:  user.Id,
+		Rating:  input.Rating,
+		Body:    input.Body,
+	}
+
+	v := validator.New()
+
+	if reviews.ValidateReview(v, review); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.reviewService.Create(review)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, reviews.ErrDuplicateReview):
+			v.AddCode("movie_id", validator.CodeAlreadyReviewed, nil, "you have already reviewed this movie")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		// movie was trashed after it was fetched
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"review": review}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) updateMovieReviewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	user := context.ContextGetUser(r)
+
+	review, err := app.reviewService.GetForUser(id, user.Id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Rating *int32  `json:"rating"`
+		Body   *string `json:"body"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	if input.Rating != nil {
+		review.Rating = *input.Rating
+	}
+
+	if input.Body != nil {
+		review.Body = *input.Body
+	}
+
+	v := validator.New()
+
+	if reviews.ValidateReview(v, review); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.reviewService.Update(review)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		case errors.Is(err, models.ErrEditConflict):
+			app.err.editConflictResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"review": review}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) deleteMovieReviewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	user := context.ContextGetUser(r)
+
+	err = app.reviewService.Delete(id, user.Id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"review": "review successfuly deleted"}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
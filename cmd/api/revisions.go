@@ -0,0 +1,200 @@
+package main
+
+import (
+	"errors"
+	"movies-api/internal/context"
+	"movies-api/internal/models"
+	"movies-api/internal/models/movies"
+	"movies-api/internal/utils"
+	"movies-api/internal/validator"
+	"net/http"
+)
+
+func (app *app) listMovieRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	// make sure movie exists so unknown ids get 404 instead of empty list
+	_, err = app.movieService.Get(id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	revisions, err := app.movieService.GetRevisions(id)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"revisions": revisions}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) showMovieRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	version, err := utils.ReadInt64Param(r, "version")
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	revision, err := app.movieService.GetRevision(id, int32(version))
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"revision": revision}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) diffMovieRevisionsHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	v := validator.New()
+
+	from := utils.ReadInt(r.URL.Query(), "from", 0, v)
+	to := utils.ReadInt(r.URL.Query(), "to", 0, v)
+
+	v.CheckCode(from > 0, "from", validator.CodeRequired, nil, "From version must be provided")
+	v.CheckCode(to > 0, "to", validator.CodeRequired, nil, "To version must be provided")
+
+	if !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	revisions := make([]*movies.Revision, 0, 2)
+
+	for _, version := range []int{from, to} {
+		revision, err := app.movieService.GetRevision(id, int32(version))
+
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrRecordNotFound):
+				app.err.notFoundResponse(w, r)
+			default:
+				app.err.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		revisions = append(revisions, revision)
+	}
+
+	env := utils.Envelope{
+		"from":    from,
+		"to":      to,
+		"changes": movies.Diff(revisions[0], revisions[1]),
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, env, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) revertMovieRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	version, err := utils.ReadInt64Param(r, "version")
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	movie, err := app.movieService.Get(id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	revision, err := app.movieService.GetRevision(id, int32(version))
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// revert creates new version with values of the old one
+	revision.Apply(movie)
+
+	v := validator.New()
+
+	if movies.ValidateMovie(v, movie); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.movieService.Update(movie, context.ContextGetUser(r).Id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrEditConflict):
+			app.err.editConflictResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"movie": movie}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
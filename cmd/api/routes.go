@@ -7,24 +7,39 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
+const suggestPattern = "/v1/movies/suggest"
+
 func (app *app) routes() http.Handler {
 	r := chi.NewRouter()
 
 	r.Use(app.metrics)
+	r.Use(app.localize)
 	r.Use(app.recoverPanic)
 	r.Use(app.enableCORS)
-	r.Use(app.rateLimit)
-	r.Use(app.authenticate)
+	// suggestions are requested on every keystroke, so they
+	// have own rate limit budget and dont eat the default one
+	r.Use(app.skipRoutes(r, app.rateLimit(app.config.limiter.rps, app.config.limiter.burst), suggestPattern))
 
 	r.NotFound(app.err.notFoundResponse)
 	r.MethodNotAllowed(app.err.notAllowedResponse)
 
 	r.Route("/v1", func(r chi.Router) {
-		r.Mount("/metrics", app.metricsRouter())
+		r.With(app.rateLimit(app.config.limiter.suggestRps, app.config.limiter.suggestBurst), app.authenticate).
+			Get("/movies/suggest", app.requirePermission("movies:read", app.suggestMoviesHandler))
+
+		r.Group(func(r chi.Router) {
+			r.Use(app.authenticate)
+
+			r.Get("/openapi.json", app.openAPIHandler)
+			r.Post("/graphql", app.graphqlHandler)
 
-		r.Mount("/movies", app.moviesRouter())
-		r.Mount("/users", app.usersRouter())
-		r.Mount("/tokens", app.tokensRouter())
+			r.Mount("/metrics", app.metricsRouter())
+
+			r.Mount("/movies", app.moviesRouter())
+			r.Mount("/people", app.peopleRouter())
+			r.Mount("/users", app.usersRouter())
+			r.Mount("/tokens", app.tokensRouter())
+		})
 	})
 
 	return r
@@ -34,13 +49,57 @@ func (app *app) routes() http.Handler {
 func (app *app) moviesRouter() http.Handler {
 	r := chi.NewRouter()
 
+	// middleware is used instead of wrapping router,
+	// so routes stay visible for chi.Walk
+	r.Use(func(next http.Handler) http.Handler {
+		return app.requireActivatedUser(next)
+	})
+
 	r.Get("/", app.requirePermission("movies:read", app.listMoviesHandler))
 	r.Post("/", app.requirePermission("movies:write", app.createMovieHandler))
+	r.Post("/import", app.requirePermission("movies:write", app.importMoviesHandler))
+	r.Get("/export", app.requirePermission("movies:read", app.exportMoviesHandler))
+	r.Get("/trash", app.requirePermission("movies:write", app.listTrashHandler))
 	r.Get("/{id}", app.requirePermission("movies:read", app.showMovieHandler))
 	r.Patch("/{id}", app.requirePermission("movies:write", app.updateMovieHandler))
 	r.Delete("/{id}", app.requirePermission("movies:write", app.deleteMovieHandler))
+	r.Post("/{id}/restore", app.requirePermission("movies:write", app.restoreMovieHandler))
+	r.Delete("/{id}/purge", app.requirePermission("movies:admin", app.purgeMovieHandler))
 
-	return app.requireActivatedUser(r)
+	r.Get("/{id}/revisions", app.requirePermission("movies:read", app.listMovieRevisionsHandler))
+	r.Get("/{id}/revisions/diff", app.requirePermission("movies:read", app.diffMovieRevisionsHandler))
+	r.Get("/{id}/revisions/{version}", app.requirePermission("movies:read", app.showMovieRevisionHandler))
+	r.Post("/{id}/revisions/{version}/revert", app.requirePermission("movies:write", app.revertMovieRevisionHandler))
+
+	r.Get("/{id}/reviews", app.requirePermission("movies:read", app.listMovieReviewsHandler))
+	r.Post("/{id}/reviews", app.requirePermission("reviews:write", app.createMovieReviewHandler))
+	r.Patch("/{id}/reviews", app.requirePermission("reviews:write", app.updateMovieReviewHandler))
+	r.Delete("/{id}/reviews", app.requirePermission("reviews:write", app.deleteMovieReviewHandler))
+
+	return r
+}
+
+// /people
+func (app *app) peopleRouter() http.Handler {
+	r := chi.NewRouter()
+
+	// middleware is used instead of wrapping router,
+	// so routes stay visible for chi.Walk
+	r.Use(func(next http.Handler) http.Handler {
+		return app.requireActivatedUser(next)
+	})
+
+	r.Get("/", app.requirePermission("movies:read", app.listPeopleHandler))
+	r.Post("/", app.requirePermission("movies:write", app.createPersonHandler))
+	r.Get("/{id}", app.requirePermission("movies:read", app.showPersonHandler))
+	r.Patch("/{id}", app.requirePermission("movies:write", app.updatePersonHandler))
+	r.Delete("/{id}", app.requirePermission("movies:write", app.deletePersonHandler))
+
+	r.Get("/{id}/credits", app.requirePermission("movies:read", app.listPersonCreditsHandler))
+	r.Post("/{id}/credits", app.requirePermission("movies:write", app.createPersonCreditHandler))
+	r.Delete("/{id}/credits/{movie_id}/{role}", app.requirePermission("movies:write", app.deletePersonCreditHandler))
+
+	return r
 }
 
 // /users
@@ -48,11 +107,27 @@ func (app *app) usersRouter() http.Handler {
 	r := chi.NewRouter()
 
 	r.Post("/", app.createUserHandler)
-	r.Get("/", app.getUserHandler)
-	r.Patch("/", app.updateUserHandler)
+	r.Get("/", app.requireAuthenticatedUser(http.HandlerFunc(app.getUserHandler)))
+	r.Patch("/", app.requireSessionUser(http.HandlerFunc(app.updateUserHandler)))
 	r.Put("/activated", app.activateUserHandler)
 	r.Put("/password", app.updateUserPasswordHandler)
 
+	r.Get("/watchlist", app.requireActivatedUser(http.HandlerFunc(app.listWatchlistHandler)))
+	r.Post("/watchlist", app.requireActivatedUser(http.HandlerFunc(app.addToWatchlistHandler)))
+	r.Delete("/watchlist/{movie_id}", app.requireActivatedUser(http.HandlerFunc(app.removeFromWatchlistHandler)))
+
+	r.Get("/history", app.requireActivatedUser(http.HandlerFunc(app.listHistoryHandler)))
+	r.Post("/history", app.requireActivatedUser(http.HandlerFunc(app.addToHistoryHandler)))
+	r.Delete("/history/{id}", app.requireActivatedUser(http.HandlerFunc(app.removeFromHistoryHandler)))
+
+	r.Post("/mfa", app.requireSessionUser(http.HandlerFunc(app.enrollMFAHandler)))
+	r.Put("/mfa/confirmed", app.requireSessionUser(http.HandlerFunc(app.confirmMFAHandler)))
+	r.Delete("/mfa", app.requireSessionUser(http.HandlerFunc(app.disableMFAHandler)))
+
+	r.Get("/api-keys", app.requireSessionUser(http.HandlerFunc(app.listAPIKeysHandler)))
+	r.Post("/api-keys", app.requireSessionUser(app.requireActivatedUser(http.HandlerFunc(app.createAPIKeyHandler))))
+	r.Delete("/api-keys/{id}", app.requireSessionUser(http.HandlerFunc(app.deleteAPIKeyHandler)))
+
 	return r
 }
 
@@ -61,9 +136,15 @@ func (app *app) tokensRouter() http.Handler {
 	r := chi.NewRouter()
 
 	r.Post("/authentication", app.createAuthTokenHandler)
+	r.Delete("/authentication", app.requireSessionUser(http.HandlerFunc(app.deleteAuthTokenHandler)))
+	r.Post("/refresh", app.refreshAuthTokenHandler)
+	r.Get("/jwks.json", app.jwksHandler)
 	r.Post("/password-reset", app.createPasswordResetTokenHandler)
 	r.Post("/activation", app.createActivationTokenHandler)
 
+	r.Get("/sessions", app.requireSessionUser(http.HandlerFunc(app.listSessionsHandler)))
+	r.Delete("/sessions/{id}", app.requireSessionUser(http.HandlerFunc(app.deleteSessionHandler)))
+
 	return r
 }
 
@@ -72,7 +153,7 @@ func (app *app) metricsRouter() http.Handler {
 	r := chi.NewRouter()
 
 	r.Get("/healthcheck", app.healthcheckHandler)
-	r.Handle("/debug/vars", expvar.Handler())
+	r.Method(http.MethodGet, "/debug/vars", expvar.Handler())
 
 	return r
 }
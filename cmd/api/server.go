@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -20,8 +21,33 @@ func (app *app) serve() error {
 		WriteTimeout: 30 * time.Second,
 	}
 
+	// gRPC API shares services with REST API but listens on its own port
+	grpcServer := app.newGRPCServer()
+
+	grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", app.config.grpc.port))
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		app.logger.PrintInfo("starting gRPC server", map[string]string{
+			"addr": grpcListener.Addr().String(),
+		})
+
+		err := grpcServer.Serve(grpcListener)
+		if err != nil {
+			app.logger.PrintError(err, nil)
+		}
+	}()
+
 	shutdownError := make(chan error)
 
+	// closed on shutdown to stop background jobs
+	done := make(chan struct{})
+
+	app.purgeTrash(done)
+	app.syncRevocations(done)
+
 	go func() {
 		quit := make(chan os.Signal, 1)
 
@@ -43,10 +69,25 @@ func (app *app) serve() error {
 			shutdownError <- err
 		}
 
+		// open streams are cut when shutdown timeout is over
+		stopped := make(chan struct{})
+
+		go func() {
+			grpcServer.GracefulStop()
+			close(stopped)
+		}()
+
+		select {
+		case <-stopped:
+		case <-ctx.Done():
+			grpcServer.Stop()
+		}
+
 		app.logger.PrintInfo("completeing background tasks on %s", map[string]string{
 			"addr": server.Addr,
 		})
 
+		close(done)
 		app.wg.Wait()
 		shutdownError <- nil
 	}()
@@ -56,7 +97,7 @@ func (app *app) serve() error {
 		"env":  app.config.env,
 	})
 
-	err := server.ListenAndServe()
+	err = server.ListenAndServe()
 	if !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
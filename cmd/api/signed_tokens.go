@@ -0,0 +1,178 @@
+package main
+
+import (
+	"errors"
+	"movies-api/internal/context"
+	"movies-api/internal/jsonlog"
+	"movies-api/internal/models"
+	"movies-api/internal/models/acttokens"
+	"movies-api/internal/models/permissions"
+	"movies-api/internal/models/users"
+	"movies-api/internal/signedtokens"
+	"movies-api/internal/utils"
+	"movies-api/internal/validator"
+	"net/http"
+)
+
+// newSigner creates signer from configured keys. Without keys random
+// key is generated, tokens signed by it do not survive restart
+func newSigner(cfg config, logger *jsonlog.Logger) (*signedtokens.Signer, error) {
+	keys, err := signedtokens.ParseKeys(cfg.auth.signingKeys)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(keys) == 0 {
+		if cfg.env == "prod" {
+			return nil, errors.New("signed token mode requires signing keys")
+		}
+
+		key, err := signedtokens.GenerateKey("dev")
+		if err != nil {
+			return nil, err
+		}
+
+		logger.PrintInfo("no signing keys configured, using random key", nil)
+
+		keys = append(keys, key)
+	}
+
+	return signedtokens.NewSigner(keys)
+}
+
+// authenticateToken finds user of bearer token. Signed tokens are
+// verified without DB and returned with their claims, user of such
+// token has only id and activation state set
+func (app *app) authenticateToken(token string) (*users.User, *signedtokens.Claims, error) {
+	if app.signer != nil && signedtokens.IsSigned(token) {
+		claims, err := app.signer.Verify(token)
+
+		if err != nil || app.revocations.IsRevoked(claims.SessionID()) {
+			return nil, nil, models.ErrRecordNotFound
+		}
+
+		return &users.User{Id: claims.UserID(), Activated: claims.Activated}, claims, nil
+	}
+
+	v := validator.New()
+
+	if acttokens.ValidateTokenPlaintext(v, token); !v.Valid() {
+		return nil, nil, models.ErrRecordNotFound
+	}
+
+	user, err := app.userService.GetByToken(acttokens.ScopeAuth, token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	err = app.actTokenService.TouchSession(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return user, nil, nil
+}
+
+// signToken replaces plaintext of authentication token with signed
+// token when signed mode is enabled. Row of token is kept as session,
+// so its id is used as id of signed token for revocation
+func (app *app) signToken(token *acttokens.ActToken) error {
+	if app.signer == nil {
+		return nil
+	}
+
+	user, err := app.userService.Get(token.UserID)
+	if err != nil {
+		return err
+	}
+
+	permissions, err := app.grantedPermissions(user)
+	if err != nil {
+		return err
+	}
+
+	token.Plaintext, err = app.signer.Sign(user.Id, token.Id, user.Activated, permissions, token.Expiry)
+
+	return err
+}
+
+// tokenPermissions returns permissions granted to api key or carried
+// by signed token, other tokens get all permissions of user from DB
+func (app *app) tokenPermissions(user *users.User, claims *signedtokens.Claims, key *acttokens.APIKey) (permissions.Permissions, error) {
+	switch {
+	case key != nil:
+		return app.mfaPermissions(user, key.Permissions), nil
+	case claims != nil:
+		return claims.Permissions, nil
+	default:
+		return app.grantedPermissions(user)
+	}
+}
+
+func (app *app) userPermissions(r *http.Request) (permissions.Permissions, error) {
+	return app.tokenPermissions(context.ContextGetUser(r), context.ContextGetClaims(r), context.ContextGetAPIKey(r))
+}
+
+// fullUser returns user of request with all fields,
+// user of signed token is loaded from DB
+func (app *app) fullUser(r *http.Request) (*users.User, error) {
+	user := context.ContextGetUser(r)
+
+	if context.ContextGetClaims(r) == nil {
+		return user, nil
+	}
+
+	return app.userService.Get(user.Id)
+}
+
+// reloadRevocations replaces revocation list with one from DB,
+// so revoked signed tokens are rejected without waiting for sync
+func (app *app) reloadRevocations() error {
+	if app.signer == nil {
+		return nil
+	}
+
+	ids, err := app.actTokenService.GetRevoked()
+	if err != nil {
+		return err
+	}
+
+	app.revocations.Replace(ids)
+
+	return nil
+}
+
+// revokeAllSessions deletes authentication and refresh tokens of user.
+// Signed tokens are revoked right away, if reload fails they are
+// revoked on next sync
+func (app *app) revokeAllSessions(userID int64) error {
+	err := app.actTokenService.DeleteAllSessions(userID)
+	if err != nil {
+		return err
+	}
+
+	if err := app.reloadRevocations(); err != nil {
+		app.logger.PrintError(err, nil)
+	}
+
+	return nil
+}
+
+// jwksHandler publishes public keys of signed tokens. Key set is
+// empty when API issues opaque tokens
+func (app *app) jwksHandler(w http.ResponseWriter, r *http.Request) {
+	keys := []signedtokens.JWK{}
+
+	if app.signer != nil {
+		keys = app.signer.JWKS()
+	}
+
+	headers := make(http.Header)
+	headers.Set("Cache-Control", "public, max-age=300")
+
+	err := utils.WriteJSON(w, http.StatusOK, utils.Envelope{"keys": keys}, headers)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
@@ -3,19 +3,28 @@ package main
 import (
 	"errors"
 	"fmt"
+	"movies-api/internal/context"
 	"movies-api/internal/models"
 	"movies-api/internal/models/acttokens"
+	"movies-api/internal/models/mfa"
 	"movies-api/internal/models/users"
 	"movies-api/internal/utils"
 	"movies-api/internal/validator"
 	"net/http"
 	"time"
+
+	"github.com/tomasen/realip"
 )
 
+// createAuthTokenHandler logs user in. Users with MFA enabled get
+// short lived mfa token instead of tokens, and send it back
+// with code from authenticator app as second step
 func (app *app) createAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
 	var input struct {
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		MFAToken string `json:"mfa_token"`
+		Code     string `json:"code"`
 	}
 
 	err := utils.ReadJSON(w, r, &input)
@@ -26,6 +35,31 @@ func (app *app) createAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
 
 	v := validator.New()
 
+	if input.MFAToken != "" {
+		if mfa.ValidateSecondStep(v, input.MFAToken, input.Code); !v.Valid() {
+			app.err.failedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		user, err := app.verifySecondFactor(input.MFAToken, input.Code)
+
+		if err != nil {
+			switch {
+			case errors.Is(err, models.ErrRecordNotFound):
+				v.AddCode("mfa_token", validator.CodeInvalidToken, nil, "invalid or expired mfa token")
+				app.err.failedValidationResponse(w, r, v.Errors)
+			case errors.Is(err, mfa.ErrInvalidCode):
+				app.err.invalidCredentialsResponse(w, r)
+			default:
+				app.err.serverErrorResponse(w, r, err)
+			}
+			return
+		}
+
+		app.writeAuthTokens(w, r, user)
+		return
+	}
+
 	users.ValidateEmail(v, input.Email)
 	users.ValidatePasswordPlaintext(v, input.Password)
 
@@ -58,14 +92,189 @@ func (app *app) createAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, err := app.actTokenService.New(user.Id, 24*time.Hour, acttokens.ScopeAuth)
+	if user.MFAEnabled {
+		token, err := app.actTokenService.New(user.Id, mfaPendingTTL, acttokens.ScopeMFAPending)
+
+		if err != nil {
+			app.err.serverErrorResponse(w, r, err)
+			return
+		}
+
+		err = utils.WriteJSON(w, http.StatusAccepted, utils.Envelope{"mfa_token": token}, nil)
+
+		if err != nil {
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	app.writeAuthTokens(w, r, user)
+}
+
+// writeAuthTokens starts new session of user and responds with its tokens
+func (app *app) writeAuthTokens(w http.ResponseWriter, r *http.Request, user *users.User) {
+	token, refreshToken, err := app.actTokenService.NewSession(user.Id, app.config.auth.accessTTL, app.config.auth.refreshTTL, r.UserAgent(), realip.FromRequest(r))
 
 	if err != nil {
 		app.err.serverErrorResponse(w, r, err)
 		return
 	}
 
-	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"auth_token": token}, nil)
+	err = app.signToken(token)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"auth_token": token, "refresh_token": refreshToken}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+// refreshAuthTokenHandler exchanges refresh token for new pair
+// of tokens. Refresh token can only be used once
+func (app *app) refreshAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Token string `json:"token"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if acttokens.ValidateTokenPlaintext(v, input.Token); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	ip := realip.FromRequest(r)
+
+	token, refreshToken, err := app.actTokenService.Refresh(input.Token, app.config.auth.accessTTL, app.config.auth.refreshTTL, r.UserAgent(), ip)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, acttokens.ErrTokenReused):
+			app.logger.PrintInfo("refresh token reused, session revoked", map[string]string{
+				"ip": ip,
+			})
+
+			if err := app.reloadRevocations(); err != nil {
+				app.err.logError(r, err)
+			}
+
+			v.AddCode("token", validator.CodeInvalidToken, nil, "invalid or expired refresh token")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, models.ErrRecordNotFound):
+			v.AddCode("token", validator.CodeInvalidToken, nil, "invalid or expired refresh token")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = app.signToken(token)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"auth_token": token, "refresh_token": refreshToken}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+// deleteAuthTokenHandler logs out by revoking token of the request
+func (app *app) deleteAuthTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var err error
+
+	// signed token has no row of its own, it is revoked with its session
+	if claims := context.ContextGetClaims(r); claims != nil {
+		err = app.actTokenService.DeleteSession(claims.UserID(), claims.SessionID())
+
+		if errors.Is(err, models.ErrRecordNotFound) {
+			err = nil
+		}
+	} else {
+		err = app.actTokenService.DeleteByPlaintext(acttokens.ScopeAuth, context.ContextGetToken(r))
+	}
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if err := app.reloadRevocations(); err != nil {
+		app.err.logError(r, err)
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "you have been logged out"}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) listSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	user := context.ContextGetUser(r)
+
+	sessions, err := app.actTokenService.GetSessions(user.Id, context.ContextGetToken(r))
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	if claims := context.ContextGetClaims(r); claims != nil {
+		for _, session := range sessions {
+			session.Current = session.Id == claims.SessionID()
+		}
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"sessions": sessions}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) deleteSessionHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	user := context.ContextGetUser(r)
+
+	err = app.actTokenService.DeleteSession(user.Id, id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	if err := app.reloadRevocations(); err != nil {
+		app.err.logError(r, err)
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "session successfully revoked"}, nil)
 
 	if err != nil {
 		app.err.serverErrorResponse(w, r, err)
@@ -96,7 +305,7 @@ func (app *app) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.R
 	if err != nil {
 		switch {
 		case errors.Is(err, models.ErrRecordNotFound):
-			v.AddError("email", "no matching email address found")
+			v.AddCode("email", validator.CodeEmailNotFound, nil, "no matching email address found")
 			app.err.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.err.serverErrorResponse(w, r, err)
@@ -105,7 +314,7 @@ func (app *app) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.R
 	}
 
 	if !user.Activated {
-		v.AddError("email", "user account must activated")
+		v.AddCode("email", validator.CodeInactiveAccount, nil, "user account must activated")
 		app.err.notAllowedResponse(w, r)
 		return
 	}
@@ -133,7 +342,7 @@ func (app *app) createPasswordResetTokenHandler(w http.ResponseWriter, r *http.R
 			"passwordResetToken": token.Plaintext,
 		}
 
-		err = app.mailer.Send(user.Email, "token_password_reset.tmpl.html", data)
+		err = app.mailer.Send(user.Email, user.Locale, "token_password_reset.tmpl.html", data)
 		if err != nil {
 			app.logger.PrintError(err, nil)
 		}
@@ -172,7 +381,7 @@ func (app *app) createActivationTokenHandler(w http.ResponseWriter, r *http.Requ
 	if err != nil {
 		switch {
 		case errors.Is(err, models.ErrRecordNotFound):
-			v.AddError("email", "no matching email address found")
+			v.AddCode("email", validator.CodeEmailNotFound, nil, "no matching email address found")
 			app.err.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.err.serverErrorResponse(w, r, err)
@@ -181,7 +390,7 @@ func (app *app) createActivationTokenHandler(w http.ResponseWriter, r *http.Requ
 	}
 
 	if user.Activated {
-		v.AddError("email", "user has been already activated")
+		v.AddCode("email", validator.CodeAlreadyActivated, nil, "user has been already activated")
 		app.err.failedValidationResponse(w, r, v.Errors)
 		return
 	}
@@ -208,7 +417,7 @@ func (app *app) createActivationTokenHandler(w http.ResponseWriter, r *http.Requ
 			"activationToken": token.Plaintext,
 		}
 
-		err = app.mailer.Send(user.Email, "token_activation.tmpl.html", data)
+		err = app.mailer.Send(user.Email, user.Locale, "token_activation.tmpl.html", data)
 		if err != nil {
 			app.logger.PrintError(err, nil)
 		}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"errors"
+	"movies-api/internal/models"
+	"movies-api/internal/models/movies"
+	"movies-api/internal/utils"
+	"movies-api/internal/validator"
+	"net/http"
+)
+
+func (app *app) listTrashHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		movies.MovieFilters
+	}
+
+	v := validator.New()
+
+	input.Page = utils.ReadInt(r.URL.Query(), "page", 1, v)
+	input.PageSize = utils.ReadInt(r.URL.Query(), "page_size", 10, v)
+
+	if movies.ValidatePagination(v, input.MovieFilters); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movies, meta, err := app.movieService.GetTrash(&input.MovieFilters)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"movies": movies, "metadata": meta}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) restoreMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.movieService.Restore(id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	movie, err := app.movieService.Get(id)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"movie": movie}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) purgeMovieHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	err = app.movieService.Purge(id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"movie": "movie successfuly purged"}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"movies-api/internal/utils"
 	"movies-api/internal/validator"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -17,6 +18,7 @@ func (app *app) createUserHandler(w http.ResponseWriter, r *http.Request) {
 		Name     string `json:"name"`
 		Email    string `json:"email"`
 		Password string `json:"password"`
+		Locale   string `json:"locale"`
 	}
 
 	err := utils.ReadJSON(w, r, &input)
@@ -25,10 +27,17 @@ func (app *app) createUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// emails are sent in locale of signup request
+	// unless user picked another one
+	if input.Locale == "" {
+		input.Locale = app.i18n.Match(r.Header.Get("Accept-Language"))
+	}
+
 	user := &users.User{
 		Name:      input.Name,
 		Email:     input.Email,
 		Activated: false,
+		Locale:    input.Locale,
 	}
 
 	err = user.Password.Set(input.Password)
@@ -39,6 +48,8 @@ func (app *app) createUserHandler(w http.ResponseWriter, r *http.Request) {
 
 	v := validator.New()
 
+	app.validateLocale(v, user.Locale)
+
 	if users.ValidateUser(v, user); !v.Valid() {
 		app.err.failedValidationResponse(w, r, v.Errors)
 		return
@@ -49,7 +60,7 @@ func (app *app) createUserHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		switch {
 		case errors.Is(err, users.ErrDuplicateEmail):
-			v.AddError("email", "user with this email already exists")
+			v.AddCode("email", validator.CodeUnique, nil, "user with this email already exists")
 			app.err.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.err.serverErrorResponse(w, r, err)
@@ -57,8 +68,8 @@ func (app *app) createUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// grand movies:read permission
-	err = app.permissionsService.AddForUser(user.Id, "movies:read")
+	// grand movies:read and reviews:write permissions
+	err = app.permissionsService.AddForUser(user.Id, "movies:read", "reviews:write")
 	if err != nil {
 		app.err.serverErrorResponse(w, r, err)
 		return
@@ -88,7 +99,7 @@ func (app *app) createUserHandler(w http.ResponseWriter, r *http.Request) {
 			"userID":          user.Id,
 		}
 
-		err = app.mailer.Send(user.Email, "user_welcome.tmpl.html", data)
+		err = app.mailer.Send(user.Email, user.Locale, "user_welcome.tmpl.html", data)
 		if err != nil {
 			app.logger.PrintError(err, nil)
 		}
@@ -101,11 +112,148 @@ func (app *app) createUserHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (app *app) getUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := app.fullUser(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	permissions, err := app.userPermissions(r)
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
 
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": user, "permissions": permissions}, nil)
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
 }
 
 func (app *app) updateUserHandler(w http.ResponseWriter, r *http.Request) {
+	user, err := app.fullUser(r)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.invalidAuthenticationTokenResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	var input struct {
+		Name     *string `json:"name"`
+		Email    *string `json:"email"`
+		Password *string `json:"password"`
+		Locale   *string `json:"locale"`
+	}
+
+	err = utils.ReadJSON(w, r, &input)
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	// if value is nil, then that means that no value was provided
+	// and we do not need to change it
+	if input.Name != nil {
+		user.Name = *input.Name
+	}
+
+	emailChanged := input.Email != nil && *input.Email != user.Email
+
+	// new email must be confirmed again
+	if emailChanged {
+		user.Email = *input.Email
+		user.Activated = false
+	}
+
+	if input.Password != nil {
+		err = user.Password.Set(*input.Password)
+		if err != nil {
+			app.err.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if input.Locale != nil {
+		user.Locale = *input.Locale
+	}
+
+	v := validator.New()
+
+	app.validateLocale(v, user.Locale)
+
+	if users.ValidateUser(v, user); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = app.userService.Update(user)
+	if err != nil {
+		switch {
+		case errors.Is(err, users.ErrDuplicateEmail):
+			v.AddCode("email", validator.CodeUnique, nil, "user with this email already exists")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		case errors.Is(err, models.ErrEditConflict):
+			app.err.editConflictResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	// sessions opened with old password are revoked,
+	// including one of this request
+	if input.Password != nil {
+		err = app.revokeAllSessions(user.Id)
+		if err != nil {
+			app.err.serverErrorResponse(w, r, err)
+			return
+		}
+	}
+
+	if emailChanged {
+		// create new activation token for changed email
+		token, err := app.actTokenService.New(user.Id, 3*24*time.Hour, acttokens.ScopeActivation)
+		if err != nil {
+			app.err.serverErrorResponse(w, r, err)
+			return
+		}
+
+		// send email in background
+		app.wg.Add(1)
+		go func() {
+			defer app.wg.Done()
+
+			// recover to catch any panics
+			defer func() {
+				if err := recover(); err != nil {
+					app.logger.PrintError(fmt.Errorf("%s", err), nil)
+				}
+			}()
+
+			data := map[string]any{
+				"activationToken": token.Plaintext,
+			}
+
+			err := app.mailer.Send(user.Email, user.Locale, "token_activation.tmpl.html", data)
+			if err != nil {
+				app.logger.PrintError(err, nil)
+			}
+		}()
+	}
 
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"user": user}, nil)
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
 }
 
 func (app *app) activateUserHandler(w http.ResponseWriter, r *http.Request) {
@@ -131,7 +279,7 @@ func (app *app) activateUserHandler(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		switch {
 		case errors.Is(err, models.ErrRecordNotFound):
-			v.AddError("token", "invalid or expired activation token")
+			v.AddCode("token", validator.CodeInvalidToken, nil, "invalid or expired activation token")
 			app.err.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.err.serverErrorResponse(w, r, err)
@@ -195,7 +343,7 @@ func (app *app) updateUserPasswordHandler(w http.ResponseWriter, r *http.Request
 	if err != nil {
 		switch {
 		case errors.Is(err, models.ErrRecordNotFound):
-			v.AddError("token", "invalid or expired password reset token")
+			v.AddCode("token", validator.CodeInvalidToken, nil, "invalid or expired password reset token")
 			app.err.failedValidationResponse(w, r, v.Errors)
 		default:
 			app.err.serverErrorResponse(w, r, err)
@@ -229,6 +377,13 @@ func (app *app) updateUserPasswordHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	// sessions opened with old password are revoked
+	err = app.revokeAllSessions(user.Id)
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
 	env := utils.Envelope{"message": "your password was successfully reset"}
 	err = utils.WriteJSON(w, http.StatusOK, env, nil)
 	if err != nil {
@@ -236,3 +391,9 @@ func (app *app) updateUserPasswordHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 }
+
+// validateLocale checks that locale is present in message catalogue
+func (app *app) validateLocale(v *validator.Validator, locale string) {
+	locales := app.i18n.Locales()
+	v.CheckCode(app.i18n.Supported(locale), "locale", validator.CodeOneOf, validator.Params{"values": locales}, "Locale must be one of: "+strings.Join(locales, ", "))
+}
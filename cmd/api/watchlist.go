@@ -0,0 +1,224 @@
+package main
+
+import (
+	"errors"
+	"movies-api/internal/context"
+	"movies-api/internal/models"
+	"movies-api/internal/models/watchlist"
+	"movies-api/internal/utils"
+	"movies-api/internal/validator"
+	"net/http"
+	"time"
+)
+
+func (app *app) listWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		watchlist.Filters
+	}
+
+	v := validator.New()
+
+	input.Page = utils.ReadInt(r.URL.Query(), "page", 1, v)
+	input.PageSize = utils.ReadInt(r.URL.Query(), "page_size", 10, v)
+
+	if watchlist.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := context.ContextGetUser(r)
+
+	entries, meta, err := app.watchlistService.GetAll(user.Id, &input.Filters)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"watchlist": entries, "metadata": meta}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) addToWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieId int64 `json:"movie_id"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.CheckCode(input.MovieId > 0, "movie_id", validator.CodeRequired, nil, "Movie id must be provided"); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := context.ContextGetUser(r)
+
+	err = app.watchlistService.Add(user.Id, input.MovieId)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, watchlist.ErrInvalidMovie):
+			v.AddCode("movie_id", validator.CodeMovieNotFound, nil, "movie with this id does not exist")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"message": "movie added to watchlist"}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) removeFromWatchlistHandler(w http.ResponseWriter, r *http.Request) {
+	movieID, err := utils.ReadInt64Param(r, "movie_id")
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	user := context.ContextGetUser(r)
+
+	err = app.watchlistService.Remove(user.Id, movieID)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "movie removed from watchlist"}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) listHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		watchlist.Filters
+	}
+
+	v := validator.New()
+
+	input.Page = utils.ReadInt(r.URL.Query(), "page", 1, v)
+	input.PageSize = utils.ReadInt(r.URL.Query(), "page_size", 10, v)
+
+	if watchlist.ValidateFilters(v, input.Filters); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := context.ContextGetUser(r)
+
+	entries, meta, err := app.historyService.GetAll(user.Id, &input.Filters)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"history": entries, "metadata": meta}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) addToHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		MovieId   int64      `json:"movie_id"`
+		WatchedAt *time.Time `json:"watched_at"`
+	}
+
+	err := utils.ReadJSON(w, r, &input)
+
+	if err != nil {
+		app.err.badRequestResponse(w, r, err)
+		return
+	}
+
+	entry := &watchlist.HistoryEntry{
+		MovieId:   input.MovieId,
+		WatchedAt: time.Now(),
+	}
+
+	if input.WatchedAt != nil {
+		entry.WatchedAt = *input.WatchedAt
+	}
+
+	v := validator.New()
+
+	if watchlist.ValidateHistoryEntry(v, entry); !v.Valid() {
+		app.err.failedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	user := context.ContextGetUser(r)
+
+	err = app.historyService.Add(user.Id, entry)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, watchlist.ErrInvalidMovie):
+			v.AddCode("movie_id", validator.CodeMovieNotFound, nil, "movie with this id does not exist")
+			app.err.failedValidationResponse(w, r, v.Errors)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusCreated, utils.Envelope{"entry": entry}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
+
+func (app *app) removeFromHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := utils.ReadIdParam(r)
+
+	if err != nil {
+		app.err.notFoundResponse(w, r)
+		return
+	}
+
+	user := context.ContextGetUser(r)
+
+	err = app.historyService.Remove(user.Id, id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrRecordNotFound):
+			app.err.notFoundResponse(w, r)
+		default:
+			app.err.serverErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = utils.WriteJSON(w, http.StatusOK, utils.Envelope{"message": "entry removed from history"}, nil)
+
+	if err != nil {
+		app.err.serverErrorResponse(w, r, err)
+	}
+}
@@ -2,21 +2,38 @@ package context
 
 import (
 	"context"
+	"movies-api/internal/models/acttokens"
 	"movies-api/internal/models/users"
+	"movies-api/internal/signedtokens"
 	"net/http"
 )
 
 type contextKey string
 
-const userContextKey = contextKey("user")
+const (
+	userContextKey   = contextKey("user")
+	localeContextKey = contextKey("locale")
+	tokenContextKey  = contextKey("token")
+	claimsContextKey = contextKey("claims")
+	apiKeyContextKey = contextKey("apiKey")
+)
 
 func ContextSetUser(r *http.Request, user *users.User) *http.Request {
-	ctx := context.WithValue(r.Context(), userContextKey, user)
-	return r.WithContext(ctx)
+	return r.WithContext(WithUser(r.Context(), user))
 }
 
 func ContextGetUser(r *http.Request) *users.User {
-	user, ok := r.Context().Value(userContextKey).(*users.User)
+	return UserFrom(r.Context())
+}
+
+// WithUser and UserFrom work with plain context
+// for handlers outside of net/http, e.g gRPC
+func WithUser(ctx context.Context, user *users.User) context.Context {
+	return context.WithValue(ctx, userContextKey, user)
+}
+
+func UserFrom(ctx context.Context) *users.User {
+	user, ok := ctx.Value(userContextKey).(*users.User)
 
 	if !ok {
 		panic("missing user value in context")
@@ -24,3 +41,56 @@ func ContextGetUser(r *http.Request) *users.User {
 
 	return user
 }
+
+func ContextSetLocale(r *http.Request, locale string) *http.Request {
+	ctx := context.WithValue(r.Context(), localeContextKey, locale)
+	return r.WithContext(ctx)
+}
+
+// ContextGetLocale returns empty string if locale was not set,
+// which catalogue treats as default locale
+func ContextGetLocale(r *http.Request) string {
+	locale, _ := r.Context().Value(localeContextKey).(string)
+
+	return locale
+}
+
+// ContextSetToken stores authentication token of request,
+// so current session can be found or revoked
+func ContextSetToken(r *http.Request, token string) *http.Request {
+	ctx := context.WithValue(r.Context(), tokenContextKey, token)
+	return r.WithContext(ctx)
+}
+
+// ContextGetToken returns empty string for anonymous requests
+func ContextGetToken(r *http.Request) string {
+	token, _ := r.Context().Value(tokenContextKey).(string)
+
+	return token
+}
+
+// ContextSetClaims stores claims of signed token,
+// user in context then has only fields from claims
+func ContextSetClaims(r *http.Request, claims *signedtokens.Claims) *http.Request {
+	ctx := context.WithValue(r.Context(), claimsContextKey, claims)
+	return r.WithContext(ctx)
+}
+
+// ContextGetClaims returns nil unless request has signed token
+func ContextGetClaims(r *http.Request) *signedtokens.Claims {
+	claims, _ := r.Context().Value(claimsContextKey).(*signedtokens.Claims)
+
+	return claims
+}
+
+func ContextSetAPIKey(r *http.Request, key *acttokens.APIKey) *http.Request {
+	ctx := context.WithValue(r.Context(), apiKeyContextKey, key)
+	return r.WithContext(ctx)
+}
+
+// ContextGetAPIKey returns nil unless request was authenticated by API key
+func ContextGetAPIKey(r *http.Request) *acttokens.APIKey {
+	key, _ := r.Context().Value(apiKeyContextKey).(*acttokens.APIKey)
+
+	return key
+}
@@ -0,0 +1,184 @@
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"movies-api/internal/validator"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed "locales"
+var localesFS embed.FS
+
+const DefaultLocale = "en"
+
+// Catalogue holds messages of every embedded locale.
+// Messages may contain {param} placeholders
+type Catalogue struct {
+	messages map[string]map[string]string
+}
+
+func New() (*Catalogue, error) {
+	files, err := localesFS.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	c := &Catalogue{messages: make(map[string]map[string]string)}
+
+	for _, file := range files {
+		data, err := localesFS.ReadFile(path.Join("locales", file.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		var messages map[string]string
+
+		err = json.Unmarshal(data, &messages)
+		if err != nil {
+			return nil, fmt.Errorf("locale %s: %w", file.Name(), err)
+		}
+
+		c.messages[strings.TrimSuffix(file.Name(), ".json")] = messages
+	}
+
+	if _, ok := c.messages[DefaultLocale]; !ok {
+		return nil, fmt.Errorf("missing default locale %s", DefaultLocale)
+	}
+
+	return c, nil
+}
+
+func (c *Catalogue) Supported(locale string) bool {
+	_, ok := c.messages[locale]
+	return ok
+}
+
+func (c *Catalogue) Locales() []string {
+	locales := make([]string, 0, len(c.messages))
+
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+
+	sort.Strings(locales)
+
+	return locales
+}
+
+// Match picks best supported locale from Accept-Language header
+// (e.g "ru-RU,ru;q=0.9,en;q=0.8"). Region is ignored when
+// catalogue has only base language
+func (c *Catalogue) Match(acceptLanguage string) string {
+	type tag struct {
+		lang string
+		q    float64
+	}
+
+	tags := []tag{}
+
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		lang, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+
+		q := 1.0
+
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+
+			q = parsed
+		}
+
+		if lang == "" || q <= 0 {
+			continue
+		}
+
+		tags = append(tags, tag{lang: strings.ToLower(lang), q: q})
+	}
+
+	sort.SliceStable(tags, func(i, j int) bool {
+		return tags[i].q > tags[j].q
+	})
+
+	for _, t := range tags {
+		if t.lang == "*" {
+			return DefaultLocale
+		}
+
+		if c.Supported(t.lang) {
+			return t.lang
+		}
+
+		base, _, _ := strings.Cut(t.lang, "-")
+
+		if c.Supported(base) {
+			return base
+		}
+	}
+
+	return DefaultLocale
+}
+
+// T returns message by key in locale. It falls back to
+// default locale and then to the key itself
+func (c *Catalogue) T(locale, key string, params map[string]any) string {
+	msg, ok := c.messages[locale][key]
+
+	if !ok {
+		msg, ok = c.messages[DefaultLocale][key]
+	}
+
+	if !ok {
+		return key
+	}
+
+	return format(msg, params)
+}
+
+// Errors translates validation errors by their codes. Messages
+// written by validators are kept when locale has no translation
+// for the code, so new code never leaves error without message
+func (c *Catalogue) Errors(locale string, errs validator.Errors) validator.Errors {
+	translated := make(validator.Errors, len(errs))
+
+	for key, fieldErrs := range errs {
+		for _, e := range fieldErrs {
+			if msg, ok := c.messages[locale]["validation."+e.Code]; ok {
+				e.Message = format(msg, e.Params)
+			}
+
+			translated[key] = append(translated[key], e)
+		}
+	}
+
+	return translated
+}
+
+func format(msg string, params map[string]any) string {
+	if len(params) == 0 {
+		return msg
+	}
+
+	replacements := make([]string, 0, len(params)*2)
+
+	for name, value := range params {
+		var s string
+
+		switch value := value.(type) {
+		case []string:
+			s = strings.Join(value, ", ")
+		default:
+			s = fmt.Sprint(value)
+		}
+
+		replacements = append(replacements, "{"+name+"}", s)
+	}
+
+	return strings.NewReplacer(replacements...).Replace(msg)
+}
@@ -0,0 +1,106 @@
+package i18n
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// TestCataloguesCoverCodes fails when validation or bad request
+// code is added without message in every locale
+func TestCataloguesCoverCodes(t *testing.T) {
+	c, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sources := map[string]string{
+		"../validator/validator.go": "validation.",
+		"../utils/helpers.go":       "error.",
+	}
+
+	for file, prefix := range sources {
+		codes := codeConstants(t, file)
+
+		if len(codes) == 0 {
+			t.Fatalf("%s has no codes", file)
+		}
+
+		for _, code := range codes {
+			for _, locale := range c.Locales() {
+				if _, ok := c.messages[locale][prefix+code]; !ok {
+					t.Errorf("locale %s has no message %s%s", locale, prefix, code)
+				}
+			}
+		}
+	}
+}
+
+// TestCataloguesHaveSameKeys fails when locale misses
+// message of default locale or has message which default lacks
+func TestCataloguesHaveSameKeys(t *testing.T) {
+	c, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, locale := range c.Locales() {
+		for key := range c.messages[DefaultLocale] {
+			if _, ok := c.messages[locale][key]; !ok {
+				t.Errorf("locale %s has no message %s", locale, key)
+			}
+		}
+
+		for key := range c.messages[locale] {
+			if _, ok := c.messages[DefaultLocale][key]; !ok {
+				t.Errorf("locale %s has message %s missing in %s", locale, key, DefaultLocale)
+			}
+		}
+	}
+}
+
+// codeConstants returns values of string constants named Code* in file
+func codeConstants(t *testing.T, file string) []string {
+	t.Helper()
+
+	f, err := parser.ParseFile(token.NewFileSet(), file, nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	codes := []string{}
+
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+
+		for _, spec := range gen.Specs {
+			value := spec.(*ast.ValueSpec)
+
+			for i, name := range value.Names {
+				if !strings.HasPrefix(name.Name, "Code") || i >= len(value.Values) {
+					continue
+				}
+
+				lit, ok := value.Values[i].(*ast.BasicLit)
+				if !ok || lit.Kind != token.STRING {
+					continue
+				}
+
+				code, err := strconv.Unquote(lit.Value)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				codes = append(codes, code)
+			}
+		}
+	}
+
+	return codes
+}
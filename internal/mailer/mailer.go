@@ -3,6 +3,7 @@ package mailer
 import (
 	"bytes"
 	"embed"
+	"io/fs"
 	"text/template"
 	"time"
 
@@ -27,9 +28,17 @@ func New(host string, port int, username, password, sender string) Mailer {
 	}
 }
 
-func (m Mailer) Send(recipient, templateFile string, data any) error {
+// Send renders template in recipient locale. Templates of default
+// locale live in templates root, translations in templates/<locale>
+func (m Mailer) Send(recipient, locale, templateFile string, data any) error {
+	templatePath := "templates/" + templateFile
+
+	if _, err := fs.Stat(templateFS, "templates/"+locale+"/"+templateFile); err == nil {
+		templatePath = "templates/" + locale + "/" + templateFile
+	}
+
 	// parse Email template
-	tmpl, err := template.New("email").ParseFS(templateFS, "templates/"+templateFile)
+	tmpl, err := template.New("email").ParseFS(templateFS, templatePath)
 
 	if err != nil {
 		return err
@@ -14,14 +14,32 @@ const (
 	ScopeActivation    = "activation"
 	ScopeAuth          = "authentication"
 	ScopePasswordReset = "password-reset"
+	ScopeRefresh       = "refresh"
+	ScopeAPIKey        = "api-key"
+	// ScopeMFAPending is issued after password was checked
+	// for users who must also enter second factor code
+	ScopeMFAPending = "mfa-pending"
 )
 
+// insertQuery is shared by Create and token pairs issued in transaction
+const insertQuery = `
+	INSERT INTO tokens (hash, user_id, expiry, scope, user_agent, ip, family)
+	VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''))
+	RETURNING id, created_at
+	`
+
 type ActToken struct {
+	Id        int64     `json:"-"`
 	Plaintext string    `json:"token"`
 	Hash      []byte    `json:"-"`
 	UserID    int64     `json:"-"`
 	Expiry    time.Time `json:"expiry"`
 	Scope     string    `json:"-"`
+	UserAgent string    `json:"-"`
+	IP        string    `json:"-"`
+	// Family links access and refresh tokens issued by one login
+	Family    string    `json:"-"`
+	CreatedAt time.Time `json:"-"`
 }
 
 type ActTokenService struct {
@@ -44,19 +62,12 @@ func (t ActTokenService) New(userID int64, ttl time.Duration, scope string) (*Ac
 }
 
 func (t ActTokenService) Create(token *ActToken) error {
-	query := `
-	INSERT INTO tokens (hash, user_id, expiry, scope)
-	VALUES ($1, $2, $3, $4)
-	`
-
-	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope}
+	args := []any{token.Hash, token.UserID, token.Expiry, token.Scope, token.UserAgent, token.IP, token.Family}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	_, err := t.DB.ExecContext(ctx, query, args...)
-
-	return err
+	return t.DB.QueryRowContext(ctx, insertQuery, args...).Scan(&token.Id, &token.CreatedAt)
 }
 
 func (t ActTokenService) DeleteAllForUser(scope string, userID int64) error {
@@ -73,8 +84,8 @@ func (t ActTokenService) DeleteAllForUser(scope string, userID int64) error {
 }
 
 func ValidateTokenPlaintext(v *validator.Validator, tokenPlaintext string) {
-	v.Check(tokenPlaintext != "", "token", "Token must be provided")
-	v.Check(len(tokenPlaintext) == 26, "token", "Token must be 26 characters")
+	v.CheckCode(tokenPlaintext != "", "token", validator.CodeRequired, nil, "Token must be provided")
+	v.CheckCode(len(tokenPlaintext) == 26, "token", validator.CodeLength, validator.Params{"length": 26}, "Token must be 26 characters")
 }
 
 func generateActToken(userID int64, ttl time.Duration, scope string) (*ActToken, error) {
@@ -84,18 +95,27 @@ func generateActToken(userID int64, ttl time.Duration, scope string) (*ActToken,
 		Scope:  scope,
 	}
 
-	randomBytes := make([]byte, 16)
+	var err error
 
-	// fill byte slice with random bytes from OS
-	_, err := rand.Read(randomBytes)
+	token.Plaintext, err = randomString()
 	if err != nil {
 		return nil, err
 	}
 
-	token.Plaintext = base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes)
-
 	hash := sha256.Sum256([]byte(token.Plaintext))
 	token.Hash = hash[:]
 
 	return token, nil
 }
+
+func randomString() (string, error) {
+	randomBytes := make([]byte, 16)
+
+	// fill byte slice with random bytes from OS
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	return base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(randomBytes), nil
+}
@@ -0,0 +1,199 @@
+package acttokens
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"movies-api/internal/models"
+	"movies-api/internal/validator"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKey is long lived token of service account. It carries
+// subset of permissions of its owner and may never expire
+type APIKey struct {
+	Id     int64  `json:"id"`
+	UserID int64  `json:"-"`
+	Name   string `json:"name"`
+	// Key is only known right after key was created
+	Key         string     `json:"key,omitempty"`
+	Permissions []string   `json:"permissions"`
+	CreatedAt   time.Time  `json:"created_at"`
+	Expiry      *time.Time `json:"expiry"`
+	LastUsedAt  *time.Time `json:"last_used_at"`
+}
+
+func ValidateAPIKey(v *validator.Validator, key *APIKey, ownerPermissions []string) {
+	v.CheckCode(key.Name != "", "name", validator.CodeRequired, nil, "Name must be provided")
+	v.CheckCode(len(key.Name) <= 100, "name", validator.CodeMaxLength, validator.Params{"max": 100}, "Name must be less than 100 characters")
+
+	v.CheckCode(len(key.Permissions) >= 1, "permissions", validator.CodeMinItems, validator.Params{"min": 1}, "Permissions must contain at least 1 permission")
+	v.CheckCode(validator.Unique(key.Permissions), "permissions", validator.CodeUnique, nil, "Permissions must not contain duplicate values")
+
+	// key can not get more than its owner has
+	for _, code := range key.Permissions {
+		if !validator.AllowedValues(code, ownerPermissions...) {
+			v.AddCode("permissions", validator.CodeOneOf, validator.Params{"values": ownerPermissions}, "Permissions must be a subset of your permissions")
+			break
+		}
+	}
+
+	if key.Expiry != nil {
+		v.CheckCode(key.Expiry.After(time.Now()), "expiry", validator.CodeFuture, nil, "Expiry must be in the future")
+	}
+}
+
+func (t ActTokenService) NewAPIKey(key *APIKey) error {
+	token, err := generateActToken(key.UserID, 0, ScopeAPIKey)
+	if err != nil {
+		return err
+	}
+
+	query := `
+	INSERT INTO tokens (hash, user_id, expiry, scope, name, permissions)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	RETURNING id, created_at`
+
+	args := []any{token.Hash, key.UserID, key.Expiry, ScopeAPIKey, key.Name, pq.Array(key.Permissions)}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err = t.DB.QueryRowContext(ctx, query, args...).Scan(&key.Id, &key.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	key.Key = token.Plaintext
+
+	return nil
+}
+
+// GetAPIKeys returns keys of user, expired ones included
+func (t ActTokenService) GetAPIKeys(userID int64) ([]*APIKey, error) {
+	query := `
+	SELECT id, user_id, name, permissions, created_at, expiry, last_used_at
+	FROM tokens
+	WHERE user_id = $1 AND scope = $2
+	ORDER BY created_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := t.DB.QueryContext(ctx, query, userID, ScopeAPIKey)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	keys := []*APIKey{}
+
+	for rows.Next() {
+		var key APIKey
+
+		err := rows.Scan(
+			&key.Id,
+			&key.UserID,
+			&key.Name,
+			pq.Array(&key.Permissions),
+			&key.CreatedAt,
+			&key.Expiry,
+			&key.LastUsedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, &key)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// GetByAPIKey finds valid key and marks it as used. Permissions of
+// returned key are limited to ones its owner still has
+func (t ActTokenService) GetByAPIKey(keyPlaintext string) (*APIKey, error) {
+	hash := sha256.Sum256([]byte(keyPlaintext))
+
+	query := `
+	WITH key AS (
+		UPDATE tokens
+		SET last_used_at = NOW()
+		WHERE hash = $1
+		AND scope = $2
+		AND (expiry IS NULL OR expiry > $3)
+		RETURNING id, user_id, name, permissions, created_at, expiry, last_used_at
+	)
+	SELECT key.id, key.user_id, key.name, key.created_at, key.expiry, key.last_used_at,
+		ARRAY(
+			SELECT permissions.code
+			FROM permissions
+			INNER JOIN users_permissions ON users_permissions.permission_id = permissions.id
+			WHERE users_permissions.user_id = key.user_id
+			AND permissions.code = ANY(key.permissions)
+		)
+	FROM key`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var key APIKey
+
+	err := t.DB.QueryRowContext(ctx, query, hash[:], ScopeAPIKey, time.Now()).Scan(
+		&key.Id,
+		&key.UserID,
+		&key.Name,
+		&key.CreatedAt,
+		&key.Expiry,
+		&key.LastUsedAt,
+		pq.Array(&key.Permissions),
+	)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, models.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &key, nil
+}
+
+func (t ActTokenService) DeleteAPIKey(userID, id int64) error {
+	query := `
+	DELETE FROM tokens
+	WHERE id = $1 AND user_id = $2 AND scope = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := t.DB.ExecContext(ctx, query, id, userID, ScopeAPIKey)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,39 @@
+package acttokens
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"movies-api/internal/models"
+	"time"
+)
+
+// Consume deletes valid token and returns id of its owner,
+// so each token can be checked only once
+func (t ActTokenService) Consume(scope, tokenPlaintext string) (int64, error) {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+	DELETE FROM tokens
+	WHERE hash = $1 AND scope = $2 AND expiry > $3
+	RETURNING user_id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var userID int64
+
+	err := t.DB.QueryRowContext(ctx, query, hash[:], scope, time.Now()).Scan(&userID)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return 0, models.ErrRecordNotFound
+		default:
+			return 0, err
+		}
+	}
+
+	return userID, nil
+}
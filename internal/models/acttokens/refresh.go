@@ -0,0 +1,170 @@
+package acttokens
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"movies-api/internal/models"
+	"time"
+)
+
+// ErrTokenReused is returned when refresh token which was
+// already exchanged is presented again
+var ErrTokenReused = errors.New("refresh token reused")
+
+// user agent is sent by client, so it is
+// cut to keep tokens table small
+const maxUserAgentLength = 512
+
+// NewSession logs user in. It creates authentication token and
+// refresh token which renews it, both remember client they were issued to
+func (t ActTokenService) NewSession(userID int64, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*ActToken, *ActToken, error) {
+	family, err := randomString()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := t.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer tx.Rollback()
+
+	access, refresh, err := insertPair(ctx, tx, userID, family, accessTTL, refreshTTL, userAgent, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return access, refresh, tx.Commit()
+}
+
+// Refresh exchanges refresh token for new pair of tokens. Exchanged
+// token is kept as used, so if it is presented again whole family
+// is revoked, because either client or attacker has stolen copy of it
+func (t ActTokenService) Refresh(refreshPlaintext string, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*ActToken, *ActToken, error) {
+	hash := sha256.Sum256([]byte(refreshPlaintext))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := t.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer tx.Rollback()
+
+	// row is locked, so concurrent requests
+	// with same token can not both rotate it
+	query := `
+	SELECT user_id, family, used_at
+	FROM tokens
+	WHERE hash = $1 AND scope = $2 AND expiry > $3
+	FOR UPDATE`
+
+	var (
+		userID int64
+		family string
+		usedAt *time.Time
+	)
+
+	err = tx.QueryRowContext(ctx, query, hash[:], ScopeRefresh, time.Now()).Scan(&userID, &family, &usedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, nil, models.ErrRecordNotFound
+		default:
+			return nil, nil, err
+		}
+	}
+
+	if usedAt != nil {
+		query = `
+		WITH deleted AS (
+			DELETE FROM tokens
+			WHERE family = $1
+			RETURNING id, scope, expiry
+		),` + revokeDeleted + `
+		SELECT count(*) FROM deleted`
+
+		_, err = tx.ExecContext(ctx, query, family)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		err = tx.Commit()
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return nil, nil, ErrTokenReused
+	}
+
+	_, err = tx.ExecContext(ctx, `UPDATE tokens SET used_at = NOW() WHERE hash = $1`, hash[:])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// previous authentication token is replaced by new one
+	query = `
+	WITH deleted AS (
+		DELETE FROM tokens
+		WHERE family = $1 AND scope = $2
+		RETURNING id, scope, expiry
+	),` + revokeDeleted + `
+	SELECT count(*) FROM deleted`
+
+	_, err = tx.ExecContext(ctx, query, family, ScopeAuth)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	access, refresh, err := insertPair(ctx, tx, userID, family, accessTTL, refreshTTL, userAgent, ip)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return access, refresh, tx.Commit()
+}
+
+func insertPair(ctx context.Context, tx *sql.Tx, userID int64, family string, accessTTL, refreshTTL time.Duration, userAgent, ip string) (*ActToken, *ActToken, error) {
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+
+	tokens := make([]*ActToken, 2)
+
+	for i, scope := range []string{ScopeAuth, ScopeRefresh} {
+		ttl := accessTTL
+
+		if scope == ScopeRefresh {
+			ttl = refreshTTL
+		}
+
+		token, err := generateActToken(userID, ttl, scope)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		token.UserAgent = userAgent
+		token.IP = ip
+		token.Family = family
+
+		args := []any{token.Hash, token.UserID, token.Expiry, token.Scope, token.UserAgent, token.IP, token.Family}
+
+		err = tx.QueryRowContext(ctx, insertQuery, args...).Scan(&token.Id, &token.CreatedAt)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		tokens[i] = token
+	}
+
+	return tokens[0], tokens[1], nil
+}
@@ -0,0 +1,72 @@
+package acttokens
+
+import (
+	"context"
+	"time"
+)
+
+// revokeDeleted is CTE which follows CTE named deleted with removed
+// tokens. Authentication tokens removed before they expired are kept
+// in revoked_tokens, because signed tokens stay valid without their rows
+const revokeDeleted = `
+	revoked AS (
+		INSERT INTO revoked_tokens (id, expiry)
+		SELECT id, expiry
+		FROM deleted
+		WHERE scope = 'authentication' AND expiry > NOW()
+		ON CONFLICT DO NOTHING
+	)`
+
+// GetRevoked returns ids of revoked authentication
+// tokens which have not expired yet
+func (t ActTokenService) GetRevoked() ([]int64, error) {
+	query := `
+	SELECT id
+	FROM revoked_tokens
+	WHERE expiry > NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := t.DB.QueryContext(ctx, query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	ids := []int64{}
+
+	for rows.Next() {
+		var id int64
+
+		err := rows.Scan(&id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		ids = append(ids, id)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return ids, nil
+}
+
+// PurgeRevoked removes revoked tokens which expired anyway
+func (t ActTokenService) PurgeRevoked() error {
+	query := `
+	DELETE FROM revoked_tokens
+	WHERE expiry <= NOW()`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query)
+
+	return err
+}
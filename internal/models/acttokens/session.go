@@ -0,0 +1,175 @@
+package acttokens
+
+import (
+	"context"
+	"crypto/sha256"
+	"movies-api/internal/models"
+	"time"
+)
+
+// Session is authentication token as its owner sees it
+type Session struct {
+	Id         int64      `json:"id"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	Expiry     time.Time  `json:"expiry"`
+	UserAgent  string     `json:"user_agent"`
+	IP         string     `json:"ip"`
+	Current    bool       `json:"current"`
+}
+
+// GetSessions returns active authentication tokens of user,
+// token with currentPlaintext is marked as current
+func (t ActTokenService) GetSessions(userID int64, currentPlaintext string) ([]*Session, error) {
+	currentHash := sha256.Sum256([]byte(currentPlaintext))
+
+	query := `
+	SELECT id, created_at, last_used_at, expiry, user_agent, ip, hash = $3
+	FROM tokens
+	WHERE user_id = $1 AND scope = $2 AND expiry > NOW()
+	ORDER BY created_at DESC, id DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := t.DB.QueryContext(ctx, query, userID, ScopeAuth, currentHash[:])
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	sessions := []*Session{}
+
+	for rows.Next() {
+		var session Session
+
+		err := rows.Scan(
+			&session.Id,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.Expiry,
+			&session.UserAgent,
+			&session.IP,
+			&session.Current,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &session)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// lastUsedPrecision is how stale last use time of session may be
+const lastUsedPrecision = time.Minute
+
+// DeleteSession revokes authentication token by its id together
+// with other tokens of its login. Tokens of other users are reported as not found
+func (t ActTokenService) DeleteSession(userID, id int64) error {
+	query := `
+	WITH session AS (
+		SELECT id, family
+		FROM tokens
+		WHERE id = $1 AND user_id = $2 AND scope = $3
+	),
+	deleted AS (
+		DELETE FROM tokens
+		USING session
+		WHERE tokens.id = session.id OR tokens.family = session.family
+		RETURNING tokens.id, tokens.scope, tokens.expiry
+	),` + revokeDeleted + `
+	SELECT count(*) FROM deleted`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var deleted int
+
+	err := t.DB.QueryRowContext(ctx, query, id, userID, ScopeAuth).Scan(&deleted)
+
+	if err != nil {
+		return err
+	}
+
+	if deleted == 0 {
+		return models.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// DeleteByPlaintext revokes token together with
+// other tokens of its login, e.g on logout
+func (t ActTokenService) DeleteByPlaintext(scope, tokenPlaintext string) error {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+	WITH session AS (
+		SELECT id, family
+		FROM tokens
+		WHERE hash = $1 AND scope = $2
+	),
+	deleted AS (
+		DELETE FROM tokens
+		USING session
+		WHERE tokens.id = session.id OR tokens.family = session.family
+		RETURNING tokens.id, tokens.scope, tokens.expiry
+	),` + revokeDeleted + `
+	SELECT count(*) FROM deleted`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query, hash[:], scope)
+
+	return err
+}
+
+// DeleteAllSessions revokes every login of user, e.g when password
+// was changed, so stolen tokens stop working
+func (t ActTokenService) DeleteAllSessions(userID int64) error {
+	query := `
+	WITH deleted AS (
+		DELETE FROM tokens
+		WHERE user_id = $1 AND scope IN ($2, $3)
+		RETURNING id, scope, expiry
+	),` + revokeDeleted + `
+	SELECT count(*) FROM deleted`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query, userID, ScopeAuth, ScopeRefresh)
+
+	return err
+}
+
+// TouchSession marks authentication token as used, so owner can see when
+// each of sessions was active. Time is only stored with lastUsedPrecision,
+// so most requests do not write to DB
+func (t ActTokenService) TouchSession(tokenPlaintext string) error {
+	hash := sha256.Sum256([]byte(tokenPlaintext))
+
+	query := `
+	UPDATE tokens
+	SET last_used_at = NOW()
+	WHERE hash = $1
+	AND scope = $2
+	AND (last_used_at IS NULL OR last_used_at < $3)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := t.DB.ExecContext(ctx, query, hash[:], ScopeAuth, time.Now().Add(-lastUsedPrecision))
+
+	return err
+}
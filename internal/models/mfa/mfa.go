@@ -0,0 +1,316 @@
+package mfa
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"movies-api/internal/models"
+	"movies-api/internal/totp"
+	"movies-api/internal/validator"
+	"strings"
+	"time"
+)
+
+const recoveryCodesCount = 10
+
+var (
+	ErrAlreadyEnabled = errors.New("mfa already enabled")
+	ErrInvalidCode    = errors.New("invalid mfa code")
+)
+
+// Enrollment is shown once when user starts enrollment,
+// secret is added to authenticator app from QR code of URI
+type Enrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+type MFAService struct {
+	db *sql.DB
+}
+
+func NewMFAService(db *sql.DB) *MFAService {
+	return &MFAService{
+		db: db,
+	}
+}
+
+// ValidateCode accepts both TOTP and recovery codes
+func ValidateCode(v *validator.Validator, code string) {
+	v.CheckCode(code != "", "code", validator.CodeRequired, nil, "Code must be provided")
+	v.CheckCode(len(code) <= 32, "code", validator.CodeMaxLength, validator.Params{"max": 32}, "Code must be less than 32 characters")
+}
+
+// ValidateSecondStep checks input of second step of login
+func ValidateSecondStep(v *validator.Validator, mfaToken, code string) {
+	v.CheckCode(len(mfaToken) == 26, "mfa_token", validator.CodeLength, validator.Params{"length": 26}, "MFA token must be 26 characters")
+
+	ValidateCode(v, code)
+}
+
+// Enroll generates new secret of user. Secret of unfinished
+// enrollment is replaced, confirmed one is kept
+func (m MFAService) Enroll(userID int64) ([]byte, error) {
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+	INSERT INTO users_totp (user_id, secret)
+	VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE
+	SET secret = EXCLUDED.secret, last_counter = 0
+	WHERE users_totp.confirmed_at IS NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.db.ExecContext(ctx, query, userID, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAffected == 0 {
+		return nil, ErrAlreadyEnabled
+	}
+
+	return secret, nil
+}
+
+// Confirm finishes enrollment with code from authenticator app
+// and returns recovery codes, they can not be shown later
+func (m MFAService) Confirm(userID int64, code string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	var secret []byte
+
+	query := `
+	SELECT secret
+	FROM users_totp
+	WHERE user_id = $1 AND confirmed_at IS NULL
+	FOR UPDATE`
+
+	err = tx.QueryRowContext(ctx, query, userID).Scan(&secret)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, models.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	counter, ok := totp.Validate(secret, code, time.Now(), 0)
+	if !ok {
+		return nil, ErrInvalidCode
+	}
+
+	query = `
+	UPDATE users_totp
+	SET confirmed_at = NOW(), last_counter = $2
+	WHERE user_id = $1`
+
+	_, err = tx.ExecContext(ctx, query, userID, counter)
+	if err != nil {
+		return nil, err
+	}
+
+	codes, err := replaceRecoveryCodes(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, tx.Commit()
+}
+
+// Verify checks second factor of user. Both TOTP and recovery
+// codes can be used only once
+func (m MFAService) Verify(userID int64, code string) error {
+	code = strings.TrimSpace(code)
+
+	if isTOTPCode(code) {
+		return m.verifyTOTP(userID, code)
+	}
+
+	return m.verifyRecoveryCode(userID, code)
+}
+
+func (m MFAService) verifyTOTP(userID int64, code string) error {
+	var (
+		secret      []byte
+		lastCounter int64
+	)
+
+	query := `
+	SELECT secret, last_counter
+	FROM users_totp
+	WHERE user_id = $1 AND confirmed_at IS NOT NULL`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.db.QueryRowContext(ctx, query, userID).Scan(&secret, &lastCounter)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrInvalidCode
+		default:
+			return err
+		}
+	}
+
+	counter, ok := totp.Validate(secret, code, time.Now(), lastCounter)
+	if !ok {
+		return ErrInvalidCode
+	}
+
+	// concurrent request could have used same code already
+	query = `
+	UPDATE users_totp
+	SET last_counter = $2
+	WHERE user_id = $1 AND last_counter < $2`
+
+	res, err := m.db.ExecContext(ctx, query, userID, counter)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+func (m MFAService) verifyRecoveryCode(userID int64, code string) error {
+	hash := hashRecoveryCode(code)
+
+	query := `
+	DELETE FROM users_recovery_codes
+	WHERE user_id = $1 AND hash = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.db.ExecContext(ctx, query, userID, hash[:])
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvalidCode
+	}
+
+	return nil
+}
+
+// Disable removes secret and recovery codes of user
+func (m MFAService) Disable(userID int64) error {
+	query := `
+	WITH codes AS (
+		DELETE FROM users_recovery_codes
+		WHERE user_id = $1
+	)
+	DELETE FROM users_totp
+	WHERE user_id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	_, err := m.db.ExecContext(ctx, query, userID)
+
+	return err
+}
+
+func replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int64) ([]string, error) {
+	_, err := tx.ExecContext(ctx, `DELETE FROM users_recovery_codes WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, 0, recoveryCodesCount)
+
+	for i := 0; i < recoveryCodesCount; i++ {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+
+		hash := hashRecoveryCode(code)
+
+		_, err = tx.ExecContext(ctx, `INSERT INTO users_recovery_codes (hash, user_id) VALUES ($1, $2)`, hash[:], userID)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// generateRecoveryCode returns code in "xxxx-xxxx" form
+func generateRecoveryCode() (string, error) {
+	randomBytes := make([]byte, 5)
+
+	_, err := rand.Read(randomBytes)
+	if err != nil {
+		return "", err
+	}
+
+	code := strings.ToLower(base32.StdEncoding.EncodeToString(randomBytes))
+
+	return code[:4] + "-" + code[4:], nil
+}
+
+// hashRecoveryCode ignores case and separators, so
+// code can be typed as it is read
+func hashRecoveryCode(code string) [32]byte {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+
+	return sha256.Sum256([]byte(code))
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totp.Digits {
+		return false
+	}
+
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+
+	return true
+}
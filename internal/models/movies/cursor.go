@@ -0,0 +1,67 @@
+package movies
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
+// cursor points to the last row of previous page. It is encoded
+// as opaque base64 string so clients dont depend on its layout
+type cursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	Id    int64  `json:"i"`
+}
+
+func encodeCursor(sort string, movie *Movie) string {
+	c := cursor{
+		Sort:  sort,
+		Value: sortValue(sort, movie),
+		Id:    movie.Id,
+	}
+
+	js, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(js)
+}
+
+func decodeCursor(s string) (*cursor, error) {
+	js, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+
+	err = json.Unmarshal(js, &c)
+	if err != nil || c.Id < 1 {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// sortValue returns value of movie column used for sorting
+// in the form postgres can compare against that column
+func sortValue(sort string, movie *Movie) string {
+	switch sort {
+	case "title", "-title":
+		return movie.Title
+	case "year", "-year":
+		return strconv.Itoa(int(movie.Year))
+	case "runtime", "-runtime":
+		return strconv.Itoa(int(movie.Runtime))
+	case "rating", "-rating":
+		return strconv.FormatFloat(movie.Rating, 'g', -1, 64)
+	case "relevance":
+		return strconv.FormatFloat(movie.Relevance, 'g', -1, 64)
+	default:
+		return strconv.FormatInt(movie.Id, 10)
+	}
+}
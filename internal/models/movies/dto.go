@@ -1,72 +1,176 @@
 package movies
 
 import (
-	"math"
+	"fmt"
 	"movies-api/internal/validator"
 	"strings"
 	"time"
+
+	"github.com/lib/pq"
 )
 
+const (
+	FacetGenres  = "genres"
+	FacetDecade  = "decade"
+	FacetRuntime = "runtime"
+
+	SearchExact = "exact"
+	SearchFuzzy = "fuzzy"
+)
+
+// relevanceExpr scores how close title is to search query. ts_rank is
+// zero when no lexeme matches, so trigram similarity covers typos
+const relevanceExpr = `(ts_rank(to_tsvector('simple', title), plainto_tsquery('simple', $1)) + similarity(title, $1))`
+
 type MovieFilters struct {
 	Title        string
+	SearchMode   string
 	Genres       []string
+	GenresMode   string
+	YearFrom     int
+	YearTo       int
+	RuntimeMin   int
+	RuntimeMax   int
+	PersonId     int64
+	UserId       int64
 	Page         int
 	PageSize     int
+	Cursor       string
+	WithTotal    bool
 	Sort         string
 	SortSafelist []string
 }
 
-type Metadata struct {
-	CurrentPage  int `json:"current_page,omitempty"`
-	PageSize     int `json:"page_size,omitempty"`
-	FirstPage    int `json:"first_page,omitempty"`
-	LastPage     int `json:"last_page,omitempty"`
-	TotalRecords int `json:"total_records,omitempty"`
+type FacetCount struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
 }
 
+type Facets map[string][]FacetCount
+
 func ValidateMovie(v *validator.Validator, movie *Movie) {
-	v.Check(movie.Title != "", "title", "Title must be provided")
-	v.Check(len(movie.Title) <= 500, "title", "Title length must be less than 500 characters")
+	v.CheckCode(movie.Title != "", "title", validator.CodeRequired, nil, "Title must be provided")
+	v.CheckCode(len(movie.Title) <= 500, "title", validator.CodeMaxLength, validator.Params{"max": 500}, "Title length must be less than 500 characters")
 
-	v.Check(movie.Year != 0, "year", "Year must be provided")
-	v.Check(movie.Year >= 1800, "year", "Year must be greater than 1800")
-	v.Check(movie.Year <= int32(time.Now().Year()), "year", "Year cant be greater than current year")
+	v.CheckCode(movie.Year != 0, "year", validator.CodeRequired, nil, "Year must be provided")
+	v.CheckCode(movie.Year >= 1800, "year", validator.CodeMin, validator.Params{"min": 1800}, "Year must be greater than 1800")
+	v.CheckCode(movie.Year <= int32(time.Now().Year()), "year", validator.CodeMax, validator.Params{"max": time.Now().Year()}, "Year cant be greater than current year")
 
-	v.Check(movie.Runtime != 0, "runtime", "Runtime must be provided")
-	v.Check(movie.Runtime > 0, "runtime", "Runtime cant be less than 0")
+	v.CheckCode(movie.Runtime != 0, "runtime", validator.CodeRequired, nil, "Runtime must be provided")
+	v.CheckCode(movie.Runtime > 0, "runtime", validator.CodeMin, validator.Params{"min": 1}, "Runtime cant be less than 0")
 
-	v.Check(movie.Genres != nil, "genres", "Genres must be provided")
-	v.Check(len(movie.Genres) >= 1, "genres", "Genres must contain at least 1 genre")
-	v.Check(len(movie.Genres) <= 5, "genres", "Genres must not contain more than 5 genres")
-	v.Check(validator.Unique(movie.Genres), "genres", "Genres must not contain duplicate values")
+	v.CheckCode(movie.Genres != nil, "genres", validator.CodeRequired, nil, "Genres must be provided")
+	v.CheckCode(len(movie.Genres) >= 1, "genres", validator.CodeMinItems, validator.Params{"min": 1}, "Genres must contain at least 1 genre")
+	v.CheckCode(len(movie.Genres) <= 5, "genres", validator.CodeMaxItems, validator.Params{"max": 5}, "Genres must not contain more than 5 genres")
+	v.CheckCode(validator.Unique(movie.Genres), "genres", validator.CodeUnique, nil, "Genres must not contain duplicate values")
 }
 
+// ValidateFilters checks filters of paginated movies listing
 func ValidateFilters(v *validator.Validator, f MovieFilters) {
-	v.Check(f.Page > 0, "page", "Page must be greater than 0")
-	v.Check(f.Page <= 10_000_000, "page", "Page must be less than 10 million")
-	v.Check(f.PageSize > 1, "page_size", "Page size must be greater than 1")
-	v.Check(f.PageSize <= 100, "page_size", "Page size must be less than 100")
-	v.Check(validator.AllowedValues(f.Sort, f.SortSafelist...), "sort", "Invalid sort value")
+	ValidateFilterCriteria(v, f)
+	ValidatePagination(v, f)
+
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor)
+
+		v.CheckCode(err == nil, "cursor", validator.CodeFormat, nil, "Invalid cursor")
+		v.CheckCode(err != nil || c.Sort == f.Sort, "cursor", validator.CodeMismatch, validator.Params{"field": "sort"}, "Cursor does not match sort value")
+		v.CheckCode(f.Page == 1, "page", validator.CodeConflicts, validator.Params{"field": "cursor"}, "Page cant be used together with cursor")
+	}
+}
+
+// ValidateFilterCriteria checks filters which select and order movies,
+// it is used alone where movies are streamed without pagination
+func ValidateFilterCriteria(v *validator.Validator, f MovieFilters) {
+	searchModes := []string{SearchExact, SearchFuzzy}
+	v.CheckCode(validator.AllowedValues(f.SearchMode, searchModes...), "search_mode", validator.CodeOneOf, validator.Params{"values": searchModes}, "Search mode must be exact or fuzzy")
+
+	genresModes := []string{"any", "all"}
+	v.CheckCode(validator.AllowedValues(f.GenresMode, genresModes...), "genres_mode", validator.CodeOneOf, validator.Params{"values": genresModes}, "Genres mode must be any or all")
+
+	v.CheckCode(f.YearFrom >= 0, "year_from", validator.CodeMin, validator.Params{"min": 0}, "Year from cant be less than 0")
+	v.CheckCode(f.YearTo >= 0, "year_to", validator.CodeMin, validator.Params{"min": 0}, "Year to cant be less than 0")
+	v.CheckCode(f.YearTo == 0 || f.YearFrom <= f.YearTo, "year_from", validator.CodeLteField, validator.Params{"field": "year_to"}, "Year from must not be greater than year to")
+
+	v.CheckCode(f.RuntimeMin >= 0, "runtime_min", validator.CodeMin, validator.Params{"min": 0}, "Runtime min cant be less than 0")
+	v.CheckCode(f.RuntimeMax >= 0, "runtime_max", validator.CodeMin, validator.Params{"min": 0}, "Runtime max cant be less than 0")
+	v.CheckCode(f.RuntimeMax == 0 || f.RuntimeMin <= f.RuntimeMax, "runtime_min", validator.CodeLteField, validator.Params{"field": "runtime_max"}, "Runtime min must not be greater than runtime max")
+
+	v.CheckCode(f.PersonId >= 0, "person", validator.CodeMin, validator.Params{"min": 0}, "Person must be a positive id")
+	v.CheckCode(validator.AllowedValues(f.Sort, f.SortSafelist...), "sort", validator.CodeOneOf, validator.Params{"values": f.SortSafelist}, "Invalid sort value")
+	v.CheckCode(f.Sort != "relevance" || f.Title != "", "sort", validator.CodeRequired, validator.Params{"field": "title"}, "Relevance sort requires title")
+}
+
+// ValidatePagination checks page and page size
+func ValidatePagination(v *validator.Validator, f MovieFilters) {
+	v.CheckCode(f.Page > 0, "page", validator.CodeMin, validator.Params{"min": 1}, "Page must be greater than 0")
+	v.CheckCode(f.Page <= 10_000_000, "page", validator.CodeMax, validator.Params{"max": 10_000_000}, "Page must be less than 10 million")
+	v.CheckCode(f.PageSize > 1, "page_size", validator.CodeMin, validator.Params{"min": 2}, "Page size must be greater than 1")
+	v.CheckCode(f.PageSize <= 100, "page_size", validator.CodeMax, validator.Params{"max": 100}, "Page size must be less than 100")
 }
 
-func calcMetadata(totalRecords, page, pageSize int) Metadata {
-	if totalRecords == 0 {
-		return Metadata{}
+func ValidateSuggest(v *validator.Validator, prefix string, limit int) {
+	v.CheckCode(prefix != "", "q", validator.CodeRequired, nil, "Query must be provided")
+	v.CheckCode(len(prefix) <= 100, "q", validator.CodeMaxLength, validator.Params{"max": 100}, "Query must be less than 100 characters")
+	v.CheckCode(limit >= 1, "limit", validator.CodeMin, validator.Params{"min": 1}, "Limit must be greater than 0")
+	v.CheckCode(limit <= 20, "limit", validator.CodeMax, validator.Params{"max": 20}, "Limit must be less than 20")
+}
+
+func ValidateFacets(v *validator.Validator, facets []string) {
+	names := []string{FacetGenres, FacetDecade, FacetRuntime}
+
+	for _, name := range facets {
+		v.CheckCode(validator.AllowedValues(name, names...), "facets", validator.CodeOneOf, validator.Params{"values": names}, "Facets must be any of genres, decade, runtime")
+	}
+
+	v.CheckCode(validator.Unique(facets), "facets", validator.CodeUnique, nil, "Facets must not contain duplicate values")
+}
+
+// where builds WHERE clause shared by listing and facets queries.
+// It uses placeholders $1-$7, so callers continue numbering from $8
+func (f MovieFilters) where() (string, []any) {
+	// @> contains, && overlaps
+	// to_tsvector breaks title in lexemes (e.g "Pulp fiction" => "pulp", "fiction")
+	// plainto_tsquery turns value into query term (e.g "Pulp fiction" => "pulp" & "fiction")
+	// @@ matches operator. check if query term matches the lexemes
+	// zero range bound means that bound is not set
+	// % matches titles with trigram similarity above pg_trgm threshold
+	where := fmt.Sprintf(`
+	WHERE deleted_at IS NULL
+	AND %s
+	AND (genres %s $2 OR $2 = '{}')
+	AND (id IN (SELECT movie_id FROM movie_credits WHERE person_id = $3) OR $3 = 0)
+	AND (year >= $4 OR $4 = 0)
+	AND (year <= $5 OR $5 = 0)
+	AND (runtime >= $6 OR $6 = 0)
+	AND (runtime <= $7 OR $7 = 0)`,
+		f.titleCondition(),
+		f.genresOperator(),
+	)
+
+	args := []any{
+		f.Title, pq.Array(f.Genres), f.PersonId,
+		f.YearFrom, f.YearTo, f.RuntimeMin, f.RuntimeMax,
 	}
 
-	return Metadata{
-		CurrentPage:  page,
-		PageSize:     pageSize,
-		FirstPage:    1,
-		LastPage:     int(math.Ceil(float64(totalRecords) / float64(pageSize))),
-		TotalRecords: totalRecords,
+	return where, args
+}
+
+func (f MovieFilters) titleCondition() string {
+	if f.SearchMode == SearchFuzzy {
+		return "(to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR title % $1 OR $1 = '')"
 	}
 
+	return "(to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')"
 }
 
 func (f MovieFilters) sortColumn() string {
 	for _, v := range f.SortSafelist {
 		if f.Sort == v {
+			if f.Sort == "relevance" {
+				return relevanceExpr
+			}
+
 			return strings.TrimPrefix(f.Sort, "-")
 		}
 	}
@@ -74,18 +178,34 @@ func (f MovieFilters) sortColumn() string {
 	panic("unsafe sort param: " + f.Sort)
 }
 
+// sortDirection returns order of sort column.
+// Relevance is always sorted from best match
 func (f MovieFilters) sortDirection() string {
-	if strings.HasPrefix(f.Sort, "-") {
+	if strings.HasPrefix(f.Sort, "-") || f.Sort == "relevance" {
 		return "DESC"
 	} else {
 		return "ASC"
 	}
 }
 
+// genresOperator returns array operator for genres filter:
+// @> when movie must have all genres, && when any of them
+func (f MovieFilters) genresOperator() string {
+	if f.GenresMode == "any" {
+		return "&&"
+	}
+
+	return "@>"
+}
+
 func (f MovieFilters) limit() int {
 	return f.PageSize
 }
 
 func (f MovieFilters) offset() int {
+	if f.Cursor != "" {
+		return 0
+	}
+
 	return (f.Page - 1) * f.PageSize
 }
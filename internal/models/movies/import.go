@@ -0,0 +1,249 @@
+package movies
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"movies-api/internal/validator"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ImportRow is a single parsed row of import file.
+// Errors contains parsing errors of the row fields
+type ImportRow struct {
+	Line   int
+	Movie  *Movie
+	Errors validator.Errors
+}
+
+// ImportColumns are columns which csv header must contain
+var ImportColumns = []string{"title", "year", "runtime", "genres"}
+
+var (
+	ErrEmptyBody      = errors.New("body cant be empty")
+	ErrMissingColumns = errors.New("csv header must contain title, year, runtime and genres columns")
+)
+
+func newImportRow(line int) *ImportRow {
+	return &ImportRow{Line: line, Movie: &Movie{}, Errors: make(validator.Errors)}
+}
+
+func (row *ImportRow) addError(key, code string, params validator.Params, msg string) {
+	row.Errors[key] = append(row.Errors[key], validator.Error{Code: code, Params: params, Message: msg})
+}
+
+// ParseCSV reads movies from CSV with header row. Genres
+// are comma separated inside of a single quoted field
+func ParseCSV(r io.Reader) ([]*ImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, ErrEmptyBody
+		}
+		return nil, err
+	}
+
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+
+	for _, name := range ImportColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, ErrMissingColumns
+		}
+	}
+
+	rows := []*ImportRow{}
+
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				row := newImportRow(parseErr.StartLine)
+				row.addError("row", validator.CodeFormat, validator.Params{"format": "csv"}, parseErr.Err.Error())
+				rows = append(rows, row)
+				continue
+			}
+			return nil, err
+		}
+
+		line, _ := reader.FieldPos(0)
+		row := newImportRow(line)
+		rows = append(rows, row)
+
+		field := func(name string) string {
+			i := columns[name]
+			if i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		row.Movie.Title = field("title")
+		row.Movie.Year = parseInt32(row, "year", field("year"))
+		row.Movie.Runtime = parseInt32(row, "runtime", field("runtime"))
+
+		if genres := field("genres"); genres != "" {
+			for _, g := range strings.Split(genres, ",") {
+				row.Movie.Genres = append(row.Movie.Genres, strings.TrimSpace(g))
+			}
+		}
+	}
+
+	return rows, nil
+}
+
+// ParseNDJSON reads movies from JSON Lines, one movie object per line
+func ParseNDJSON(r io.Reader) ([]*ImportRow, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	rows := []*ImportRow{}
+	line := 0
+
+	for scanner.Scan() {
+		line++
+
+		data := bytes.TrimSpace(scanner.Bytes())
+		if len(data) == 0 {
+			continue
+		}
+
+		var input struct {
+			Title   string   `json:"title"`
+			Year    int32    `json:"year"`
+			Runtime int32    `json:"runtime"`
+			Genres  []string `json:"genres"`
+		}
+
+		row := newImportRow(line)
+		rows = append(rows, row)
+
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.DisallowUnknownFields()
+
+		err := decoder.Decode(&input)
+		if err != nil {
+			row.addError("row", validator.CodeFormat, validator.Params{"format": "json"}, fmt.Sprintf("contains bad JSON: %s", err))
+			continue
+		}
+
+		row.Movie.Title = input.Title
+		row.Movie.Year = input.Year
+		row.Movie.Runtime = input.Runtime
+		row.Movie.Genres = input.Genres
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if line == 0 {
+		return nil, ErrEmptyBody
+	}
+
+	return rows, nil
+}
+
+// Import inserts all movies in a single transaction. Either all movies
+// are inserted or none of them. Rows are copied to temporary table first,
+// COPY does not return ids, so moving them to movies with RETURNING
+// writes revisions for exactly the imported rows
+func (m MovieService) Import(movies []*Movie, editorID int64) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+	CREATE TEMP TABLE movies_import (
+		title text NOT NULL,
+		year integer NOT NULL,
+		runtime integer NOT NULL,
+		genres text[] NOT NULL
+	) ON COMMIT DROP`)
+	if err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, pq.CopyIn("movies_import", "title", "year", "runtime", "genres"))
+	if err != nil {
+		return err
+	}
+
+	for _, movie := range movies {
+		_, err = stmt.ExecContext(ctx, movie.Title, movie.Year, movie.Runtime, pq.Array(movie.Genres))
+		if err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+
+	// flush buffered rows
+	_, err = stmt.ExecContext(ctx)
+	if err != nil {
+		stmt.Close()
+		return err
+	}
+
+	err = stmt.Close()
+	if err != nil {
+		return err
+	}
+
+	query := `
+	WITH imported AS (
+		INSERT INTO movies (title, year, runtime, genres)
+		SELECT title, year, runtime, genres
+		FROM movies_import
+		RETURNING id, version, title, year, runtime, genres
+	)
+	INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, editor_id)
+	SELECT id, version, title, year, runtime, genres, $1
+	FROM imported`
+
+	_, err = tx.ExecContext(ctx, query, editorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func parseInt32(row *ImportRow, key, s string) int32 {
+	if s == "" {
+		return 0
+	}
+
+	i, err := strconv.ParseInt(s, 10, 32)
+	if err != nil {
+		row.addError(key, validator.CodeType, validator.Params{"type": "integer"}, "must be an integer value")
+		return 0
+	}
+
+	return int32(i)
+}
@@ -6,30 +6,49 @@ import (
 	"errors"
 	"fmt"
 	"movies-api/internal/models"
+	"movies-api/internal/models/people"
+	"strings"
 	"time"
 
 	"github.com/lib/pq"
 )
 
 type Movie struct {
-	Id        int64     `json:"id"`
-	Title     string    `json:"title"`
-	Year      int32     `json:"year,omitempty"`
-	Runtime   int32     `json:"runtime,omitempty"`
-	Genres    []string  `json:"genres,omitempty"`
-	CreatedAt time.Time `json:"-"`
-	Version   int32     `json:"version"`
+	Id        int64            `json:"id"`
+	Title     string           `json:"title"`
+	Year      int32            `json:"year,omitempty"`
+	Runtime   int32            `json:"runtime,omitempty"`
+	Genres    []string         `json:"genres,omitempty"`
+	Credits   []*people.Credit `json:"credits,omitempty"`
+	Rating    float64          `json:"rating"`
+	Votes     int32            `json:"votes"`
+	Watched   *bool            `json:"watched,omitempty"`
+	Relevance float64          `json:"relevance,omitempty"`
+	DeletedAt *time.Time       `json:"deleted_at,omitempty"`
+	CreatedAt time.Time        `json:"-"`
+	Version   int32            `json:"version"`
+}
+
+type Suggestion struct {
+	Id    int64  `json:"id"`
+	Title string `json:"title"`
+	Year  int32  `json:"year,omitempty"`
 }
 
 type MovieService struct {
 	db *sql.DB
 }
 
+var (
+	likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+)
+
 func NewMovieService(db *sql.DB) *MovieService {
 	return &MovieService{db: db}
 }
 
-func (m MovieService) Create(movie *Movie) error {
+// Create inserts movie and stores its first revision made by editor
+func (m MovieService) Create(movie *Movie, editorID int64) error {
 	query := `
 	INSERT INTO movies (title, year, runtime, genres) 
 	VALUES ($1, $2, $3, $4)
@@ -40,9 +59,27 @@ func (m MovieService) Create(movie *Movie) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	return m.db.
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.
 		QueryRowContext(ctx, query, args...).
 		Scan(&movie.Id, &movie.CreatedAt, &movie.Version)
+
+	if err != nil {
+		return err
+	}
+
+	err = insertRevision(ctx, tx, movie, editorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
 func (m MovieService) Get(id int64) (*Movie, error) {
@@ -53,9 +90,9 @@ func (m MovieService) Get(id int64) (*Movie, error) {
 	var movie Movie
 
 	query := `
-	SELECT id, created_at, title, year, runtime, genres, version
+	SELECT id, created_at, title, year, runtime, genres, rating, votes, version
 	FROM movies
-	WHERE id = $1`
+	WHERE id = $1 AND deleted_at IS NULL`
 
 	// create context that will release after 5 second
 	// if db query is not completed
@@ -71,6 +108,8 @@ func (m MovieService) Get(id int64) (*Movie, error) {
 			&movie.Year,
 			&movie.Runtime,
 			pq.Array(&movie.Genres),
+			&movie.Rating,
+			&movie.Votes,
 			&movie.Version,
 		)
 
@@ -85,11 +124,13 @@ func (m MovieService) Get(id int64) (*Movie, error) {
 	return &movie, nil
 }
 
-func (m MovieService) Update(movie *Movie) error {
+// Update saves movie if it was not changed since it was read
+// and stores new revision made by editor
+func (m MovieService) Update(movie *Movie, editorID int64) error {
 	query := `
 	UPDATE movies
 	SET title = $1, year = $2, runtime = $3, genres = $4, version = version + 1
-	WHERE id = $5 AND version = $6
+	WHERE id = $5 AND version = $6 AND deleted_at IS NULL
 	RETURNING version`
 
 	args := []any{
@@ -104,7 +145,14 @@ func (m MovieService) Update(movie *Movie) error {
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	err := m.db.
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.
 		QueryRowContext(ctx, query, args...).
 		Scan(&movie.Version)
 
@@ -117,23 +165,32 @@ func (m MovieService) Update(movie *Movie) error {
 		}
 	}
 
-	return nil
+	err = insertRevision(ctx, tx, movie, editorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-func (m MovieService) Delete(id int64) error {
+// Delete moves movie to trash. When version is not zero
+// movie is deleted only if it was not changed since then
+func (m MovieService) Delete(id int64, version int32) error {
 	if id < 1 {
 		return models.ErrRecordNotFound
 	}
 
-	query := ` 
-	DELETE FROM movies
-	WHERE id = $1
-	`
+	// movie is moved to trash and can be restored
+	// until it is purged
+	query := `
+	UPDATE movies
+	SET deleted_at = NOW()
+	WHERE id = $1 AND (version = $2 OR $2 = 0) AND deleted_at IS NULL`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	res, err := m.db.ExecContext(ctx, query, id)
+	res, err := m.db.ExecContext(ctx, query, id, version)
 
 	if err != nil {
 		return err
@@ -152,18 +209,51 @@ func (m MovieService) Delete(id int64) error {
 	return nil
 }
 
-func (m MovieService) GetAll(filters *MovieFilters) ([]*Movie, Metadata, error) {
-	// @> contains
-	// to_tsvector breaks title in lexemes (e.g "Pulp fiction" => "pulp", "fiction")
-	// plainto_tsquery turns value into query term (e.g "Pulp fiction" => "pulp" & "fiction")
-	// @@ matches operator. check if query term matches the lexemes
+func (m MovieService) GetAll(filters *MovieFilters) ([]*Movie, models.Metadata, error) {
+	where, args := filters.where()
+
+	// counting all matches is expensive on deep pages,
+	// so total is calculated only when requested. Window
+	// would count only rows after cursor, so cursor pages
+	// count matches in separate subquery
+	totalExpr := "0"
+	switch {
+	case filters.WithTotal && filters.Cursor != "":
+		totalExpr = fmt.Sprintf("(SELECT COUNT(*) FROM movies %s)", where)
+	case filters.WithTotal:
+		totalExpr = "COUNT(*) OVER()"
+	}
+
+	// keyset condition continues right after the row cursor points to.
+	// id is always ascending tiebreak, so only sort column changes direction
+	cursorCond := ""
+	if filters.Cursor != "" {
+		op := ">"
+		if filters.sortDirection() == "DESC" {
+			op = "<"
+		}
+
+		cursorCond = fmt.Sprintf("AND (%[1]s %[2]s $11 OR (%[1]s = $11 AND id > $12))", filters.sortColumn(), op)
+	}
+
+	relevanceSelect := "0"
+	if filters.Title != "" {
+		relevanceSelect = relevanceExpr
+	}
+
 	query := fmt.Sprintf(`
-	SELECT COUNT(*) OVER(), id, title, year, runtime, genres, created_at, version
+	SELECT %s, id, title, year, runtime, genres, rating, votes, created_at, version,
+		EXISTS (SELECT 1 FROM watch_history WHERE user_id = $8 AND movie_id = movies.id),
+		%s
 	FROM movies
-	WHERE (to_tsvector('simple', title) @@ plainto_tsquery('simple', $1) OR $1 = '')
-	AND (genres @> $2 OR $2 = '{}')
+	%s
+	%s
 	ORDER BY %s %s, id ASC
-	LIMIT $3 OFFSET $4`,
+	LIMIT $9 OFFSET $10`,
+		totalExpr,
+		relevanceSelect,
+		where,
+		cursorCond,
 		filters.sortColumn(),
 		filters.sortDirection(),
 	)
@@ -171,12 +261,22 @@ func (m MovieService) GetAll(filters *MovieFilters) ([]*Movie, Metadata, error)
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{filters.Title, pq.Array(filters.Genres), filters.limit(), filters.offset()}
+	// one extra row is fetched to know if there is next page
+	args = append(args, filters.UserId, filters.limit()+1, filters.offset())
+
+	if filters.Cursor != "" {
+		c, err := decodeCursor(filters.Cursor)
+		if err != nil {
+			return nil, models.Metadata{}, err
+		}
+
+		args = append(args, c.Value, c.Id)
+	}
 
 	rows, err := m.db.QueryContext(ctx, query, args...)
 
 	if err != nil {
-		return nil, Metadata{}, err
+		return nil, models.Metadata{}, err
 	}
 
 	defer rows.Close()
@@ -194,22 +294,232 @@ func (m MovieService) GetAll(filters *MovieFilters) ([]*Movie, Metadata, error)
 			&mov.Year,
 			&mov.Runtime,
 			pq.Array(&mov.Genres),
+			&mov.Rating,
+			&mov.Votes,
 			&mov.CreatedAt,
 			&mov.Version,
+			&mov.Watched,
+			&mov.Relevance,
 		)
 
 		if err != nil {
-			return nil, Metadata{}, err
+			return nil, models.Metadata{}, err
 		}
 
 		movies = append(movies, mov)
 	}
 
 	if err = rows.Err(); err != nil {
-		return nil, Metadata{}, err
+		return nil, models.Metadata{}, err
 	}
 
-	metadata := calcMetadata(totalRecords, filters.Page, filters.PageSize)
+	hasNext := len(movies) > filters.limit()
+	if hasNext {
+		movies = movies[:filters.limit()]
+	}
+
+	var metadata models.Metadata
+
+	// cursor pages have no numbers, so only total
+	// and next cursor describe them
+	switch {
+	case filters.Cursor != "":
+		metadata.TotalRecords = totalRecords
+	case filters.WithTotal:
+		metadata = models.CalcMetadata(totalRecords, filters.Page, filters.PageSize)
+	case len(movies) > 0:
+		metadata = models.Metadata{CurrentPage: filters.Page, PageSize: filters.PageSize, FirstPage: 1}
+	}
+
+	if hasNext {
+		metadata.NextCursor = encodeCursor(filters.Sort, movies[len(movies)-1])
+	}
 
 	return movies, metadata, nil
 }
+
+// Export calls fn for every movie matching filters. Rows are read from
+// the connection one by one, so whole result is never kept in memory.
+// Pagination of filters is ignored
+func (m MovieService) Export(ctx context.Context, filters *MovieFilters, fn func(*Movie) error) error {
+	where, args := filters.where()
+
+	query := fmt.Sprintf(`
+	SELECT id, title, year, runtime, genres, rating, votes, created_at, version
+	FROM movies
+	%s
+	ORDER BY %s %s, id ASC`,
+		where,
+		filters.sortColumn(),
+		filters.sortDirection(),
+	)
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		mov := &Movie{}
+
+		err := rows.Scan(
+			&mov.Id,
+			&mov.Title,
+			&mov.Year,
+			&mov.Runtime,
+			pq.Array(&mov.Genres),
+			&mov.Rating,
+			&mov.Votes,
+			&mov.CreatedAt,
+			&mov.Version,
+		)
+
+		if err != nil {
+			return err
+		}
+
+		err = fn(mov)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetFacets counts movies matching filters grouped by requested facets.
+// Pagination and sorting of filters are ignored
+func (m MovieService) GetFacets(filters *MovieFilters, names []string) (Facets, error) {
+	where, args := filters.where()
+
+	facets := Facets{}
+
+	for _, name := range names {
+		var query string
+
+		switch name {
+		case FacetGenres:
+			query = fmt.Sprintf(`
+			SELECT genre, COUNT(*)
+			FROM movies
+			CROSS JOIN LATERAL unnest(genres) AS genre
+			%s
+			GROUP BY genre
+			ORDER BY COUNT(*) DESC, genre ASC`, where)
+		case FacetDecade:
+			query = fmt.Sprintf(`
+			SELECT (year / 10 * 10)::text || 's', COUNT(*)
+			FROM movies
+			%s
+			GROUP BY year / 10
+			ORDER BY year / 10 ASC`, where)
+		case FacetRuntime:
+			query = fmt.Sprintf(`
+			SELECT
+				CASE
+					WHEN runtime < 90 THEN '<90'
+					WHEN runtime < 120 THEN '90-119'
+					WHEN runtime < 150 THEN '120-149'
+					ELSE '150+'
+				END AS bucket,
+				COUNT(*)
+			FROM movies
+			%s
+			GROUP BY bucket
+			ORDER BY MIN(runtime) ASC`, where)
+		default:
+			panic("unknown facet: " + name)
+		}
+
+		counts, err := m.countFacet(query, args)
+		if err != nil {
+			return nil, err
+		}
+
+		facets[name] = counts
+	}
+
+	return facets, nil
+}
+
+func (m MovieService) countFacet(query string, args []any) ([]FacetCount, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.db.QueryContext(ctx, query, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	counts := []FacetCount{}
+
+	for rows.Next() {
+		var fc FacetCount
+
+		err := rows.Scan(&fc.Value, &fc.Count)
+
+		if err != nil {
+			return nil, err
+		}
+
+		counts = append(counts, fc)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// Suggest returns movies which titles start with prefix.
+// Lookup is served by movies_title_prefix_idx index
+func (m MovieService) Suggest(prefix string, limit int) ([]*Suggestion, error) {
+	query := `
+	SELECT id, title, year
+	FROM movies
+	WHERE lower(title) LIKE $1 AND deleted_at IS NULL
+	ORDER BY votes DESC, title ASC, id ASC
+	LIMIT $2`
+
+	// escape LIKE wildcards so prefix is matched literally
+	pattern := likeEscaper.Replace(strings.ToLower(prefix)) + "%"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.db.QueryContext(ctx, query, pattern, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	suggestions := []*Suggestion{}
+
+	for rows.Next() {
+		s := &Suggestion{}
+
+		err := rows.Scan(&s.Id, &s.Title, &s.Year)
+
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,182 @@
+package movies
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"movies-api/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type Revision struct {
+	MovieId   int64     `json:"movie_id"`
+	Version   int32     `json:"version"`
+	Title     string    `json:"title"`
+	Year      int32     `json:"year"`
+	Runtime   int32     `json:"runtime"`
+	Genres    []string  `json:"genres"`
+	EditorId  *int64    `json:"editor_id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type Change struct {
+	From any `json:"from"`
+	To   any `json:"to"`
+}
+
+func (m MovieService) GetRevisions(movieID int64) ([]*Revision, error) {
+	query := `
+	SELECT movie_id, version, title, year, runtime, genres, editor_id, created_at
+	FROM movie_revisions
+	WHERE movie_id = $1
+	ORDER BY version DESC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.db.QueryContext(ctx, query, movieID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	revisions := []*Revision{}
+
+	for rows.Next() {
+		rev := &Revision{}
+
+		err := rows.Scan(
+			&rev.MovieId,
+			&rev.Version,
+			&rev.Title,
+			&rev.Year,
+			&rev.Runtime,
+			pq.Array(&rev.Genres),
+			&rev.EditorId,
+			&rev.CreatedAt,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		revisions = append(revisions, rev)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (m MovieService) GetRevision(movieID int64, version int32) (*Revision, error) {
+	if movieID < 1 || version < 1 {
+		return nil, models.ErrRecordNotFound
+	}
+
+	var rev Revision
+
+	// revisions of trashed movie are hidden like the movie itself
+	query := `
+	SELECT movie_id, version, title, year, runtime, genres, editor_id, created_at
+	FROM movie_revisions
+	WHERE movie_id = $1 AND version = $2
+	AND EXISTS (SELECT 1 FROM movies WHERE id = $1 AND deleted_at IS NULL)`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := m.db.
+		QueryRowContext(ctx, query, movieID, version).
+		Scan(
+			&rev.MovieId,
+			&rev.Version,
+			&rev.Title,
+			&rev.Year,
+			&rev.Runtime,
+			pq.Array(&rev.Genres),
+			&rev.EditorId,
+			&rev.CreatedAt,
+		)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, models.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &rev, nil
+}
+
+// Diff returns fields which differ between two revisions
+func Diff(from, to *Revision) map[string]Change {
+	changes := make(map[string]Change)
+
+	if from.Title != to.Title {
+		changes["title"] = Change{From: from.Title, To: to.Title}
+	}
+
+	if from.Year != to.Year {
+		changes["year"] = Change{From: from.Year, To: to.Year}
+	}
+
+	if from.Runtime != to.Runtime {
+		changes["runtime"] = Change{From: from.Runtime, To: to.Runtime}
+	}
+
+	if !equalStrings(from.Genres, to.Genres) {
+		changes["genres"] = Change{From: from.Genres, To: to.Genres}
+	}
+
+	return changes
+}
+
+// Apply copies revision values to movie
+func (rev *Revision) Apply(movie *Movie) {
+	movie.Title = rev.Title
+	movie.Year = rev.Year
+	movie.Runtime = rev.Runtime
+	movie.Genres = rev.Genres
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func insertRevision(ctx context.Context, tx *sql.Tx, movie *Movie, editorID int64) error {
+	query := `
+	INSERT INTO movie_revisions (movie_id, version, title, year, runtime, genres, editor_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7)`
+
+	args := []any{
+		movie.Id,
+		movie.Version,
+		movie.Title,
+		movie.Year,
+		movie.Runtime,
+		pq.Array(movie.Genres),
+		editorID,
+	}
+
+	_, err := tx.ExecContext(ctx, query, args...)
+
+	return err
+}
@@ -0,0 +1,133 @@
+package movies
+
+import (
+	"context"
+	"movies-api/internal/models"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// GetTrash returns deleted movies starting from recently deleted
+func (m MovieService) GetTrash(filters *MovieFilters) ([]*Movie, models.Metadata, error) {
+	query := `
+	SELECT COUNT(*) OVER(), id, title, year, runtime, genres, rating, votes, created_at, version, deleted_at
+	FROM movies
+	WHERE deleted_at IS NOT NULL
+	ORDER BY deleted_at DESC, id ASC
+	LIMIT $1 OFFSET $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := m.db.QueryContext(ctx, query, filters.limit(), filters.offset())
+
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	movies := []*Movie{}
+
+	for rows.Next() {
+		mov := &Movie{}
+
+		err := rows.Scan(
+			&totalRecords,
+			&mov.Id,
+			&mov.Title,
+			&mov.Year,
+			&mov.Runtime,
+			pq.Array(&mov.Genres),
+			&mov.Rating,
+			&mov.Votes,
+			&mov.CreatedAt,
+			&mov.Version,
+			&mov.DeletedAt,
+		)
+
+		if err != nil {
+			return nil, models.Metadata{}, err
+		}
+
+		movies = append(movies, mov)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	metadata := models.CalcMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return movies, metadata, nil
+}
+
+func (m MovieService) Restore(id int64) error {
+	if id < 1 {
+		return models.ErrRecordNotFound
+	}
+
+	query := `
+	UPDATE movies
+	SET deleted_at = NULL
+	WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	return m.execAffectingOne(query, id)
+}
+
+// Purge permanently removes movie from trash
+func (m MovieService) Purge(id int64) error {
+	if id < 1 {
+		return models.ErrRecordNotFound
+	}
+
+	query := `
+	DELETE FROM movies
+	WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	return m.execAffectingOne(query, id)
+}
+
+// PurgeDeleted permanently removes movies which are
+// in trash longer than retention and returns their count
+func (m MovieService) PurgeDeleted(retention time.Duration) (int64, error) {
+	query := `
+	DELETE FROM movies
+	WHERE deleted_at < $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	res, err := m.db.ExecContext(ctx, query, time.Now().Add(-retention))
+
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func (m MovieService) execAffectingOne(query string, args ...any) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := m.db.ExecContext(ctx, query, args...)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package people
+
+import (
+	"movies-api/internal/validator"
+	"time"
+)
+
+const (
+	RoleActor    = "actor"
+	RoleDirector = "director"
+	RoleWriter   = "writer"
+)
+
+type PeopleFilters struct {
+	Name     string
+	Page     int
+	PageSize int
+}
+
+func ValidatePerson(v *validator.Validator, person *Person) {
+	v.CheckCode(person.Name != "", "name", validator.CodeRequired, nil, "Name must be provided")
+	v.CheckCode(len(person.Name) <= 500, "name", validator.CodeMaxLength, validator.Params{"max": 500}, "Name length must be less than 500 characters")
+
+	if person.BirthYear != 0 {
+		v.CheckCode(person.BirthYear >= 1800, "birth_year", validator.CodeMin, validator.Params{"min": 1800}, "Birth year must be greater than 1800")
+		v.CheckCode(person.BirthYear <= int32(time.Now().Year()), "birth_year", validator.CodeMax, validator.Params{"max": time.Now().Year()}, "Birth year cant be greater than current year")
+	}
+}
+
+func ValidateCredit(v *validator.Validator, credit *Credit) {
+	v.CheckCode(credit.MovieId > 0, "movie_id", validator.CodeRequired, nil, "Movie id must be provided")
+
+	roles := []string{RoleActor, RoleDirector, RoleWriter}
+	v.CheckCode(validator.AllowedValues(credit.Role, roles...), "role", validator.CodeOneOf, validator.Params{"values": roles}, "Role must be one of actor, director, writer")
+
+	v.CheckCode(len(credit.Character) <= 500, "character", validator.CodeMaxLength, validator.Params{"max": 500}, "Character length must be less than 500 characters")
+	v.CheckCode(credit.BillingOrder >= 0, "billing_order", validator.CodeMin, validator.Params{"min": 0}, "Billing order cant be less than 0")
+}
+
+func ValidateFilters(v *validator.Validator, f PeopleFilters) {
+	v.CheckCode(f.Page > 0, "page", validator.CodeMin, validator.Params{"min": 1}, "Page must be greater than 0")
+	v.CheckCode(f.Page <= 10_000_000, "page", validator.CodeMax, validator.Params{"max": 10_000_000}, "Page must be less than 10 million")
+	v.CheckCode(f.PageSize > 1, "page_size", validator.CodeMin, validator.Params{"min": 2}, "Page size must be greater than 1")
+	v.CheckCode(f.PageSize <= 100, "page_size", validator.CodeMax, validator.Params{"max": 100}, "Page size must be less than 100")
+}
+
+func (f PeopleFilters) limit() int {
+	return f.PageSize
+}
+
+func (f PeopleFilters) offset() int {
+	return (f.Page - 1) * f.PageSize
+}
@@ -0,0 +1,366 @@
+package people
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"movies-api/internal/models"
+	"strings"
+	"time"
+)
+
+type Person struct {
+	Id        int64     `json:"id"`
+	Name      string    `json:"name"`
+	BirthYear int32     `json:"birth_year,omitempty"`
+	CreatedAt time.Time `json:"-"`
+	Version   int32     `json:"version"`
+}
+
+type Credit struct {
+	MovieId      int64  `json:"movie_id"`
+	MovieTitle   string `json:"movie_title,omitempty"`
+	PersonId     int64  `json:"person_id"`
+	Name         string `json:"name,omitempty"`
+	Role         string `json:"role"`
+	Character    string `json:"character,omitempty"`
+	BillingOrder int32  `json:"billing_order"`
+}
+
+type PeopleService struct {
+	db *sql.DB
+}
+
+var (
+	ErrDuplicateCredit = errors.New("duplicate credit")
+	ErrInvalidMovie    = errors.New("invalid movie")
+)
+
+func NewPeopleService(db *sql.DB) *PeopleService {
+	return &PeopleService{db: db}
+}
+
+func (p PeopleService) Create(person *Person) error {
+	query := `
+	INSERT INTO people (name, birth_year)
+	VALUES ($1, $2)
+	RETURNING id, created_at, version`
+
+	args := []any{person.Name, person.BirthYear}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	return p.db.
+		QueryRowContext(ctx, query, args...).
+		Scan(&person.Id, &person.CreatedAt, &person.Version)
+}
+
+func (p PeopleService) Get(id int64) (*Person, error) {
+	if id < 1 {
+		return nil, models.ErrRecordNotFound
+	}
+
+	var person Person
+
+	query := `
+	SELECT id, created_at, name, birth_year, version
+	FROM people
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := p.db.
+		QueryRowContext(ctx, query, id).
+		Scan(
+			&person.Id,
+			&person.CreatedAt,
+			&person.Name,
+			&person.BirthYear,
+			&person.Version,
+		)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, models.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &person, nil
+}
+
+func (p PeopleService) Update(person *Person) error {
+	query := `
+	UPDATE people
+	SET name = $1, birth_year = $2, version = version + 1
+	WHERE id = $3 AND version = $4
+	RETURNING version`
+
+	args := []any{person.Name, person.BirthYear, person.Id, person.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := p.db.
+		QueryRowContext(ctx, query, args...).
+		Scan(&person.Version)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return models.ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p PeopleService) Delete(id int64) error {
+	if id < 1 {
+		return models.ErrRecordNotFound
+	}
+
+	query := `
+	DELETE FROM people
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := p.db.ExecContext(ctx, query, id)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (p PeopleService) GetAll(filters *PeopleFilters) ([]*Person, models.Metadata, error) {
+	query := `
+	SELECT COUNT(*) OVER(), id, created_at, name, birth_year, version
+	FROM people
+	WHERE (name ILIKE '%' || $1 || '%' OR $1 = '')
+	ORDER BY name ASC, id ASC
+	LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, query, filters.Name, filters.limit(), filters.offset())
+
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	people := []*Person{}
+
+	for rows.Next() {
+		person := &Person{}
+
+		err := rows.Scan(
+			&totalRecords,
+			&person.Id,
+			&person.CreatedAt,
+			&person.Name,
+			&person.BirthYear,
+			&person.Version,
+		)
+
+		if err != nil {
+			return nil, models.Metadata{}, err
+		}
+
+		people = append(people, person)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	metadata := models.CalcMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return people, metadata, nil
+}
+
+// AddCredit adds person to cast or crew of movie,
+// movies in trash can not get new credits
+func (p PeopleService) AddCredit(credit *Credit) error {
+	query := `
+	INSERT INTO movie_credits (movie_id, person_id, role, character, billing_order)
+	SELECT id, $2, $3, $4, $5
+	FROM movies
+	WHERE id = $1 AND deleted_at IS NULL`
+
+	args := []any{credit.MovieId, credit.PersonId, credit.Role, credit.Character, credit.BillingOrder}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := p.db.ExecContext(ctx, query, args...)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "movie_credits_pkey"`:
+			return ErrDuplicateCredit
+		case strings.HasPrefix(err.Error(), `pq: insert or update on table "movie_credits" violates foreign key constraint "movie_credits_movie_id_fkey"`):
+			return ErrInvalidMovie
+		default:
+			return err
+		}
+	}
+
+	rowsAffected, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return ErrInvalidMovie
+	}
+
+	return nil
+}
+
+func (p PeopleService) DeleteCredit(personID, movieID int64, role string) error {
+	query := `
+	DELETE FROM movie_credits
+	WHERE person_id = $1 AND movie_id = $2 AND role = $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := p.db.ExecContext(ctx, query, personID, movieID, role)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (p PeopleService) GetCreditsForMovie(movieID int64) ([]*Credit, error) {
+	query := `
+	SELECT movie_credits.movie_id, movie_credits.person_id, people.name,
+		movie_credits.role, movie_credits.character, movie_credits.billing_order
+	FROM movie_credits
+	INNER JOIN people ON people.id = movie_credits.person_id
+	WHERE movie_credits.movie_id = $1
+	ORDER BY movie_credits.billing_order ASC, people.name ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, query, movieID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		credit := &Credit{}
+
+		err := rows.Scan(
+			&credit.MovieId,
+			&credit.PersonId,
+			&credit.Name,
+			&credit.Role,
+			&credit.Character,
+			&credit.BillingOrder,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		credits = append(credits, credit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
+
+func (p PeopleService) GetCreditsForPerson(personID int64) ([]*Credit, error) {
+	query := `
+	SELECT movie_credits.movie_id, movies.title, movie_credits.person_id,
+		movie_credits.role, movie_credits.character, movie_credits.billing_order
+	FROM movie_credits
+	INNER JOIN movies ON movies.id = movie_credits.movie_id
+	WHERE movie_credits.person_id = $1 AND movies.deleted_at IS NULL
+	ORDER BY movies.year DESC, movies.id ASC`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := p.db.QueryContext(ctx, query, personID)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	credits := []*Credit{}
+
+	for rows.Next() {
+		credit := &Credit{}
+
+		err := rows.Scan(
+			&credit.MovieId,
+			&credit.MovieTitle,
+			&credit.PersonId,
+			&credit.Role,
+			&credit.Character,
+			&credit.BillingOrder,
+		)
+
+		if err != nil {
+			return nil, err
+		}
+
+		credits = append(credits, credit)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return credits, nil
+}
@@ -0,0 +1,29 @@
+package reviews
+
+import "movies-api/internal/validator"
+
+type ReviewFilters struct {
+	Page     int
+	PageSize int
+}
+
+func ValidateReview(v *validator.Validator, review *Review) {
+	v.CheckCode(review.Rating >= 1, "rating", validator.CodeMin, validator.Params{"min": 1}, "Rating must be greater or equal to 1")
+	v.CheckCode(review.Rating <= 10, "rating", validator.CodeMax, validator.Params{"max": 10}, "Rating must be less or equal to 10")
+	v.CheckCode(len(review.Body) <= 10_000, "body", validator.CodeMaxLength, validator.Params{"max": 10_000}, "Review must be less than 10000 characters")
+}
+
+func ValidateFilters(v *validator.Validator, f ReviewFilters) {
+	v.CheckCode(f.Page > 0, "page", validator.CodeMin, validator.Params{"min": 1}, "Page must be greater than 0")
+	v.CheckCode(f.Page <= 10_000_000, "page", validator.CodeMax, validator.Params{"max": 10_000_000}, "Page must be less than 10 million")
+	v.CheckCode(f.PageSize > 1, "page_size", validator.CodeMin, validator.Params{"min": 2}, "Page size must be greater than 1")
+	v.CheckCode(f.PageSize <= 100, "page_size", validator.CodeMax, validator.Params{"max": 100}, "Page size must be less than 100")
+}
+
+func (f ReviewFilters) limit() int {
+	return f.PageSize
+}
+
+func (f ReviewFilters) offset() int {
+	return (f.Page - 1) * f.PageSize
+}
@@ -0,0 +1,296 @@
+package reviews
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"movies-api/internal/models"
+	"time"
+)
+
+type Review struct {
+	Id        int64     `json:"id"`
+	MovieId   int64     `json:"movie_id"`
+	UserId    int64     `json:"user_id"`
+	Rating    int32     `json:"rating"`
+	Body      string    `json:"body,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int32     `json:"version"`
+}
+
+type ReviewService struct {
+	db *sql.DB
+}
+
+var (
+	ErrDuplicateReview = errors.New("duplicate review")
+)
+
+func NewReviewService(db *sql.DB) *ReviewService {
+	return &ReviewService{db: db}
+}
+
+func (rs ReviewService) Create(review *Review) error {
+	query := `
+	INSERT INTO reviews (movie_id, user_id, rating, body)
+	VALUES ($1, $2, $3, $4)
+	RETURNING id, created_at, version`
+
+	args := []any{review.MovieId, review.UserId, review.Rating, review.Body}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := rs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = lockMovie(ctx, tx, review.MovieId)
+	if err != nil {
+		return err
+	}
+
+	err = tx.
+		QueryRowContext(ctx, query, args...).
+		Scan(&review.Id, &review.CreatedAt, &review.Version)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "reviews_movie_id_user_id_key"`:
+			return ErrDuplicateReview
+		default:
+			return err
+		}
+	}
+
+	err = refreshMovieRating(ctx, tx, review.MovieId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (rs ReviewService) GetForUser(movieID, userID int64) (*Review, error) {
+	var review Review
+
+	query := `
+	SELECT id, movie_id, user_id, rating, body, created_at, version
+	FROM reviews
+	WHERE movie_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := rs.db.
+		QueryRowContext(ctx, query, movieID, userID).
+		Scan(
+			&review.Id,
+			&review.MovieId,
+			&review.UserId,
+			&review.Rating,
+			&review.Body,
+			&review.CreatedAt,
+			&review.Version,
+		)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, models.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &review, nil
+}
+
+func (rs ReviewService) Update(review *Review) error {
+	query := `
+	UPDATE reviews
+	SET rating = $1, body = $2, version = version + 1
+	WHERE id = $3 AND version = $4
+	RETURNING version`
+
+	args := []any{review.Rating, review.Body, review.Id, review.Version}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := rs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = lockMovie(ctx, tx, review.MovieId)
+	if err != nil {
+		return err
+	}
+
+	err = tx.
+		QueryRowContext(ctx, query, args...).
+		Scan(&review.Version)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return models.ErrEditConflict
+		default:
+			return err
+		}
+	}
+
+	err = refreshMovieRating(ctx, tx, review.MovieId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (rs ReviewService) Delete(movieID, userID int64) error {
+	query := `
+	DELETE FROM reviews
+	WHERE movie_id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := rs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = lockMovie(ctx, tx, movieID)
+	if err != nil {
+		return err
+	}
+
+	res, err := tx.ExecContext(ctx, query, movieID, userID)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrRecordNotFound
+	}
+
+	err = refreshMovieRating(ctx, tx, movieID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (rs ReviewService) GetAllForMovie(movieID int64, filters *ReviewFilters) ([]*Review, models.Metadata, error) {
+	query := `
+	SELECT COUNT(*) OVER(), id, movie_id, user_id, rating, body, created_at, version
+	FROM reviews
+	WHERE movie_id = $1
+	ORDER BY created_at DESC, id ASC
+	LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := rs.db.QueryContext(ctx, query, movieID, filters.limit(), filters.offset())
+
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	reviews := []*Review{}
+
+	for rows.Next() {
+		review := &Review{}
+
+		err := rows.Scan(
+			&totalRecords,
+			&review.Id,
+			&review.MovieId,
+			&review.UserId,
+			&review.Rating,
+			&review.Body,
+			&review.CreatedAt,
+			&review.Version,
+		)
+
+		if err != nil {
+			return nil, models.Metadata{}, err
+		}
+
+		reviews = append(reviews, review)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	metadata := models.CalcMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return reviews, metadata, nil
+}
+
+// lockMovie locks row of the movie until transaction ends, so
+// concurrent review changes refresh its rating one after another
+// and none of them computes average from stale set of reviews.
+// Trashed movies can not be reviewed, so they are not found
+func lockMovie(ctx context.Context, tx *sql.Tx, movieID int64) error {
+	query := `
+	SELECT 1
+	FROM movies
+	WHERE id = $1 AND deleted_at IS NULL
+	FOR UPDATE`
+
+	var exists int
+
+	err := tx.QueryRowContext(ctx, query, movieID).Scan(&exists)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return models.ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
+
+// refreshMovieRating recalculates denormalized rating and votes
+// columns of movie so listings can be sorted by rating cheaply
+func refreshMovieRating(ctx context.Context, tx *sql.Tx, movieID int64) error {
+	query := `
+	UPDATE movies
+	SET rating = COALESCE(r.rating, 0), votes = COALESCE(r.votes, 0)
+	FROM (
+		SELECT AVG(rating)::float8 AS rating, COUNT(*) AS votes
+		FROM reviews
+		WHERE movie_id = $1
+	) r
+	WHERE movies.id = $1`
+
+	_, err := tx.ExecContext(ctx, query, movieID)
+
+	return err
+}
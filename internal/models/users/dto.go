@@ -41,19 +41,19 @@ func (p *Password) Matches(plainTextPass string) (bool, error) {
 }
 
 func ValidateEmail(v *validator.Validator, email string) {
-	v.Check(email != "", "email", "Email must be provided")
-	v.Check(validator.Matches(email, validator.EmailRegex), "email", "Email must be valid")
+	v.CheckCode(email != "", "email", validator.CodeRequired, nil, "Email must be provided")
+	v.CheckCode(validator.Matches(email, validator.EmailRegex), "email", validator.CodeFormat, validator.Params{"format": "email"}, "Email must be valid")
 }
 
 func ValidatePasswordPlaintext(v *validator.Validator, password string) {
-	v.Check(password != "", "password", "Password must be provided")
-	v.Check(len(password) >= 8, "password", "Password must be greater than 8 characters")
-	v.Check(len(password) <= 72, "password", "Password must be greater than 72 characters")
+	v.CheckCode(password != "", "password", validator.CodeRequired, nil, "Password must be provided")
+	v.CheckCode(len(password) >= 8, "password", validator.CodeMinLength, validator.Params{"min": 8}, "Password must be greater than 8 characters")
+	v.CheckCode(len(password) <= 72, "password", validator.CodeMaxLength, validator.Params{"max": 72}, "Password must be less than 72 characters")
 }
 
 func ValidateUser(v *validator.Validator, user *User) {
-	v.Check(user.Name != "", "name", "Name must be provided")
-	v.Check(len(user.Name) <= 500, "name", "Name must be less than 500 characters")
+	v.CheckCode(user.Name != "", "name", validator.CodeRequired, nil, "Name must be provided")
+	v.CheckCode(len(user.Name) <= 500, "name", validator.CodeMaxLength, validator.Params{"max": 500}, "Name must be less than 500 characters")
 
 	ValidateEmail(v, user.Email)
 
@@ -16,6 +16,8 @@ type User struct {
 	Password   Password  `json:"-"`
 	Created_at time.Time `json:"created_at"`
 	Activated  bool      `json:"activated"`
+	Locale     string    `json:"locale"`
+	MFAEnabled bool      `json:"mfa_enabled"`
 	Version    int       `json:"-"`
 }
 
@@ -23,6 +25,12 @@ type UserService struct {
 	db *sql.DB
 }
 
+// mfaEnabledColumn selects whether user finished TOTP enrollment
+const mfaEnabledColumn = `EXISTS (
+		SELECT 1 FROM users_totp
+		WHERE users_totp.user_id = users.id AND users_totp.confirmed_at IS NOT NULL
+	)`
+
 var (
 	ErrDuplicateEmail = errors.New("duplicate email")
 	AnonUser          = &User{}
@@ -36,11 +44,11 @@ func NewUserService(db *sql.DB) *UserService {
 
 func (u UserService) Create(user *User) error {
 	query := `
-	INSERT INTO users (name, email, password_hash, activated)
-	VALUES ($1, $2, $3, $4)
+	INSERT INTO users (name, email, password_hash, activated, locale)
+	VALUES ($1, $2, $3, $4, $5)
 	RETURNING id, created_at, version`
 
-	args := []any{user.Name, user.Email, user.Password.hash, user.Activated}
+	args := []any{user.Name, user.Email, user.Password.hash, user.Activated, user.Locale}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
@@ -61,11 +69,48 @@ func (u UserService) Create(user *User) error {
 	return nil
 }
 
+func (u UserService) Get(id int64) (*User, error) {
+	var user User
+
+	query := `
+	SELECT id, name, email, password_hash, activated, locale, created_at, version, ` + mfaEnabledColumn + `
+	FROM users
+	WHERE id = $1`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	err := u.db.
+		QueryRowContext(ctx, query, id).
+		Scan(
+			&user.Id,
+			&user.Name,
+			&user.Email,
+			&user.Password.hash,
+			&user.Activated,
+			&user.Locale,
+			&user.Created_at,
+			&user.Version,
+			&user.MFAEnabled,
+		)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, models.ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
+
 func (u UserService) GetByEmail(email string) (*User, error) {
 	var user User
 
 	query := `
-	SELECT id, name, email, password_hash, activated, created_at, version
+	SELECT id, name, email, password_hash, activated, locale, created_at, version, ` + mfaEnabledColumn + `
 	FROM users
 	WHERE email = $1`
 
@@ -80,8 +125,10 @@ func (u UserService) GetByEmail(email string) (*User, error) {
 			&user.Email,
 			&user.Password.hash,
 			&user.Activated,
+			&user.Locale,
 			&user.Created_at,
 			&user.Version,
+			&user.MFAEnabled,
 		)
 
 	if err != nil {
@@ -99,14 +146,14 @@ func (u UserService) GetByEmail(email string) (*User, error) {
 func (u UserService) Update(user *User) error {
 	query := `
 	UPDATE users
-	SET name = $1, email = $2, password_hash = $3, activated = $4, version = version + 1 
-	WHERE id = $5 AND version = $6
+	SET name = $1, email = $2, password_hash = $3, activated = $4, locale = $5, version = version + 1 
+	WHERE id = $6 AND version = $7
 	RETURNING version`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	args := []any{user.Name, user.Email, user.Password.hash, user.Activated, user.Id, user.Version}
+	args := []any{user.Name, user.Email, user.Password.hash, user.Activated, user.Locale, user.Id, user.Version}
 
 	err := u.db.
 		QueryRowContext(ctx, query, args...).
@@ -117,7 +164,7 @@ func (u UserService) Update(user *User) error {
 		case err.Error() == `pq: duplicate key value violates unique constraint "users_email_key"`:
 			return ErrDuplicateEmail
 		case errors.Is(err, sql.ErrNoRows):
-			return models.ErrRecordNotFound
+			return models.ErrEditConflict
 		default:
 			return err
 		}
@@ -132,12 +179,12 @@ func (u UserService) GetByToken(scope, tokenPlainttext string) (*User, error) {
 	var user User
 
 	query := `
-	SELECT users.id, users.name, users.email, users.password_hash, users.activated, users.created_at, users.version
+	SELECT users.id, users.name, users.email, users.password_hash, users.activated, users.locale, users.created_at, users.version, ` + mfaEnabledColumn + `
 	FROM users
 	INNER JOIN tokens
 	ON users.id = tokens.user_id
-	WHERE tokens.hash = $1 
-	AND tokens.scope = $2 
+	WHERE tokens.hash = $1
+	AND tokens.scope = $2
 	AND tokens.expiry > $3`
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
@@ -151,8 +198,10 @@ func (u UserService) GetByToken(scope, tokenPlainttext string) (*User, error) {
 		&user.Email,
 		&user.Password.hash,
 		&user.Activated,
+		&user.Locale,
 		&user.Created_at,
 		&user.Version,
+		&user.MFAEnabled,
 	)
 
 	if err != nil {
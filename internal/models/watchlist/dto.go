@@ -0,0 +1,31 @@
+package watchlist
+
+import (
+	"movies-api/internal/validator"
+	"time"
+)
+
+type Filters struct {
+	Page     int
+	PageSize int
+}
+
+func ValidateHistoryEntry(v *validator.Validator, entry *HistoryEntry) {
+	v.CheckCode(entry.MovieId > 0, "movie_id", validator.CodeRequired, nil, "Movie id must be provided")
+	v.CheckCode(!entry.WatchedAt.After(time.Now()), "watched_at", validator.CodeNotFuture, nil, "Watched at cant be in the future")
+}
+
+func ValidateFilters(v *validator.Validator, f Filters) {
+	v.CheckCode(f.Page > 0, "page", validator.CodeMin, validator.Params{"min": 1}, "Page must be greater than 0")
+	v.CheckCode(f.Page <= 10_000_000, "page", validator.CodeMax, validator.Params{"max": 10_000_000}, "Page must be less than 10 million")
+	v.CheckCode(f.PageSize > 1, "page_size", validator.CodeMin, validator.Params{"min": 2}, "Page size must be greater than 1")
+	v.CheckCode(f.PageSize <= 100, "page_size", validator.CodeMax, validator.Params{"max": 100}, "Page size must be less than 100")
+}
+
+func (f Filters) limit() int {
+	return f.PageSize
+}
+
+func (f Filters) offset() int {
+	return (f.Page - 1) * f.PageSize
+}
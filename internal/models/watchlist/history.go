@@ -0,0 +1,148 @@
+package watchlist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"movies-api/internal/models"
+	"strings"
+	"time"
+)
+
+type HistoryEntry struct {
+	Id        int64     `json:"id"`
+	MovieId   int64     `json:"movie_id"`
+	Title     string    `json:"title,omitempty"`
+	Year      int32     `json:"year,omitempty"`
+	WatchedAt time.Time `json:"watched_at"`
+}
+
+type HistoryService struct {
+	db *sql.DB
+}
+
+func NewHistoryService(db *sql.DB) *HistoryService {
+	return &HistoryService{db: db}
+}
+
+// Add logs watched movie and removes it from
+// user watchlist since it was already watched.
+// Movies in trash can not be logged
+func (hs HistoryService) Add(userID int64, entry *HistoryEntry) error {
+	query := `
+	INSERT INTO watch_history (user_id, movie_id, watched_at)
+	SELECT $1, id, $3
+	FROM movies
+	WHERE id = $2 AND deleted_at IS NULL
+	RETURNING id`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	tx, err := hs.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	err = tx.
+		QueryRowContext(ctx, query, userID, entry.MovieId, entry.WatchedAt).
+		Scan(&entry.Id)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return ErrInvalidMovie
+		case strings.HasPrefix(err.Error(), `pq: insert or update on table "watch_history" violates foreign key constraint "watch_history_movie_id_fkey"`):
+			return ErrInvalidMovie
+		default:
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `DELETE FROM watchlist WHERE user_id = $1 AND movie_id = $2`, userID, entry.MovieId)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (hs HistoryService) Remove(userID, id int64) error {
+	query := `
+	DELETE FROM watch_history
+	WHERE id = $1 AND user_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := hs.db.ExecContext(ctx, query, id, userID)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (hs HistoryService) GetAll(userID int64, filters *Filters) ([]*HistoryEntry, models.Metadata, error) {
+	query := `
+	SELECT COUNT(*) OVER(), watch_history.id, movies.id, movies.title, movies.year, watch_history.watched_at
+	FROM watch_history
+	INNER JOIN movies ON movies.id = watch_history.movie_id
+	WHERE watch_history.user_id = $1 AND movies.deleted_at IS NULL
+	ORDER BY watch_history.watched_at DESC, watch_history.id DESC
+	LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := hs.db.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*HistoryEntry{}
+
+	for rows.Next() {
+		entry := &HistoryEntry{}
+
+		err := rows.Scan(
+			&totalRecords,
+			&entry.Id,
+			&entry.MovieId,
+			&entry.Title,
+			&entry.Year,
+			&entry.WatchedAt,
+		)
+
+		if err != nil {
+			return nil, models.Metadata{}, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	metadata := models.CalcMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
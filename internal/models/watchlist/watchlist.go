@@ -0,0 +1,146 @@
+package watchlist
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"movies-api/internal/models"
+	"strings"
+	"time"
+)
+
+type Entry struct {
+	MovieId int64     `json:"movie_id"`
+	Title   string    `json:"title"`
+	Year    int32     `json:"year,omitempty"`
+	AddedAt time.Time `json:"added_at"`
+}
+
+type WatchlistService struct {
+	db *sql.DB
+}
+
+var (
+	ErrInvalidMovie = errors.New("invalid movie")
+)
+
+func NewWatchlistService(db *sql.DB) *WatchlistService {
+	return &WatchlistService{db: db}
+}
+
+// Add puts movie in user watchlist. Adding the same
+// movie twice keeps the original entry. Movies in
+// trash can not be added
+func (ws WatchlistService) Add(userID, movieID int64) error {
+	query := `
+	WITH movie AS (
+		SELECT id
+		FROM movies
+		WHERE id = $2 AND deleted_at IS NULL
+	),
+	inserted AS (
+		INSERT INTO watchlist (user_id, movie_id)
+		SELECT $1, id FROM movie
+		ON CONFLICT (user_id, movie_id) DO NOTHING
+	)
+	SELECT count(*) FROM movie`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	var found int
+
+	err := ws.db.QueryRowContext(ctx, query, userID, movieID).Scan(&found)
+
+	if err != nil {
+		switch {
+		case strings.HasPrefix(err.Error(), `pq: insert or update on table "watchlist" violates foreign key constraint "watchlist_movie_id_fkey"`):
+			return ErrInvalidMovie
+		default:
+			return err
+		}
+	}
+
+	if found == 0 {
+		return ErrInvalidMovie
+	}
+
+	return nil
+}
+
+func (ws WatchlistService) Remove(userID, movieID int64) error {
+	query := `
+	DELETE FROM watchlist
+	WHERE user_id = $1 AND movie_id = $2`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	res, err := ws.db.ExecContext(ctx, query, userID, movieID)
+
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := res.RowsAffected()
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return models.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (ws WatchlistService) GetAll(userID int64, filters *Filters) ([]*Entry, models.Metadata, error) {
+	query := `
+	SELECT COUNT(*) OVER(), movies.id, movies.title, movies.year, watchlist.added_at
+	FROM watchlist
+	INNER JOIN movies ON movies.id = watchlist.movie_id
+	WHERE watchlist.user_id = $1 AND movies.deleted_at IS NULL
+	ORDER BY watchlist.added_at DESC, movies.id ASC
+	LIMIT $2 OFFSET $3`
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+
+	rows, err := ws.db.QueryContext(ctx, query, userID, filters.limit(), filters.offset())
+
+	if err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	defer rows.Close()
+
+	totalRecords := 0
+	entries := []*Entry{}
+
+	for rows.Next() {
+		entry := &Entry{}
+
+		err := rows.Scan(
+			&totalRecords,
+			&entry.MovieId,
+			&entry.Title,
+			&entry.Year,
+			&entry.AddedAt,
+		)
+
+		if err != nil {
+			return nil, models.Metadata{}, err
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, models.Metadata{}, err
+	}
+
+	metadata := models.CalcMetadata(totalRecords, filters.Page, filters.PageSize)
+
+	return entries, metadata, nil
+}
@@ -0,0 +1,287 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: movies.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	MoviesService_Get_FullMethodName    = "/movies.v1.MoviesService/Get"
+	MoviesService_List_FullMethodName   = "/movies.v1.MoviesService/List"
+	MoviesService_Create_FullMethodName = "/movies.v1.MoviesService/Create"
+	MoviesService_Update_FullMethodName = "/movies.v1.MoviesService/Update"
+	MoviesService_Delete_FullMethodName = "/movies.v1.MoviesService/Delete"
+)
+
+// MoviesServiceClient is the client API for MoviesService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type MoviesServiceClient interface {
+	Get(ctx context.Context, in *GetMovieRequest, opts ...grpc.CallOption) (*Movie, error)
+	// List streams every movie matching filters
+	List(ctx context.Context, in *ListMoviesRequest, opts ...grpc.CallOption) (MoviesService_ListClient, error)
+	Create(ctx context.Context, in *CreateMovieRequest, opts ...grpc.CallOption) (*Movie, error)
+	Update(ctx context.Context, in *UpdateMovieRequest, opts ...grpc.CallOption) (*Movie, error)
+	Delete(ctx context.Context, in *DeleteMovieRequest, opts ...grpc.CallOption) (*DeleteMovieResponse, error)
+}
+
+type moviesServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMoviesServiceClient(cc grpc.ClientConnInterface) MoviesServiceClient {
+	return &moviesServiceClient{cc}
+}
+
+func (c *moviesServiceClient) Get(ctx context.Context, in *GetMovieRequest, opts ...grpc.CallOption) (*Movie, error) {
+	out := new(Movie)
+	err := c.cc.Invoke(ctx, MoviesService_Get_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *moviesServiceClient) List(ctx context.Context, in *ListMoviesRequest, opts ...grpc.CallOption) (MoviesService_ListClient, error) {
+	stream, err := c.cc.NewStream(ctx, &MoviesService_ServiceDesc.Streams[0], MoviesService_List_FullMethodName, opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &moviesServiceListClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type MoviesService_ListClient interface {
+	Recv() (*Movie, error)
+	grpc.ClientStream
+}
+
+type moviesServiceListClient struct {
+	grpc.ClientStream
+}
+
+func (x *moviesServiceListClient) Recv() (*Movie, error) {
+	m := new(Movie)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *moviesServiceClient) Create(ctx context.Context, in *CreateMovieRequest, opts ...grpc.CallOption) (*Movie, error) {
+	out := new(Movie)
+	err := c.cc.Invoke(ctx, MoviesService_Create_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *moviesServiceClient) Update(ctx context.Context, in *UpdateMovieRequest, opts ...grpc.CallOption) (*Movie, error) {
+	out := new(Movie)
+	err := c.cc.Invoke(ctx, MoviesService_Update_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *moviesServiceClient) Delete(ctx context.Context, in *DeleteMovieRequest, opts ...grpc.CallOption) (*DeleteMovieResponse, error) {
+	out := new(DeleteMovieResponse)
+	err := c.cc.Invoke(ctx, MoviesService_Delete_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// MoviesServiceServer is the server API for MoviesService service.
+// All implementations must embed UnimplementedMoviesServiceServer
+// for forward compatibility
+type MoviesServiceServer interface {
+	Get(context.Context, *GetMovieRequest) (*Movie, error)
+	// List streams every movie matching filters
+	List(*ListMoviesRequest, MoviesService_ListServer) error
+	Create(context.Context, *CreateMovieRequest) (*Movie, error)
+	Update(context.Context, *UpdateMovieRequest) (*Movie, error)
+	Delete(context.Context, *DeleteMovieRequest) (*DeleteMovieResponse, error)
+	mustEmbedUnimplementedMoviesServiceServer()
+}
+
+// UnimplementedMoviesServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedMoviesServiceServer struct {
+}
+
+func (UnimplementedMoviesServiceServer) Get(context.Context, *GetMovieRequest) (*Movie, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Get not implemented")
+}
+func (UnimplementedMoviesServiceServer) List(*ListMoviesRequest, MoviesService_ListServer) error {
+	return status.Errorf(codes.Unimplemented, "method List not implemented")
+}
+func (UnimplementedMoviesServiceServer) Create(context.Context, *CreateMovieRequest) (*Movie, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Create not implemented")
+}
+func (UnimplementedMoviesServiceServer) Update(context.Context, *UpdateMovieRequest) (*Movie, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Update not implemented")
+}
+func (UnimplementedMoviesServiceServer) Delete(context.Context, *DeleteMovieRequest) (*DeleteMovieResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Delete not implemented")
+}
+func (UnimplementedMoviesServiceServer) mustEmbedUnimplementedMoviesServiceServer() {}
+
+// UnsafeMoviesServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MoviesServiceServer will
+// result in compilation errors.
+type UnsafeMoviesServiceServer interface {
+	mustEmbedUnimplementedMoviesServiceServer()
+}
+
+func RegisterMoviesServiceServer(s grpc.ServiceRegistrar, srv MoviesServiceServer) {
+	s.RegisterService(&MoviesService_ServiceDesc, srv)
+}
+
+func _MoviesService_Get_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetMovieRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MoviesServiceServer).Get(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MoviesService_Get_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MoviesServiceServer).Get(ctx, req.(*GetMovieRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MoviesService_List_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ListMoviesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MoviesServiceServer).List(m, &moviesServiceListServer{stream})
+}
+
+type MoviesService_ListServer interface {
+	Send(*Movie) error
+	grpc.ServerStream
+}
+
+type moviesServiceListServer struct {
+	grpc.ServerStream
+}
+
+func (x *moviesServiceListServer) Send(m *Movie) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _MoviesService_Create_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateMovieRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MoviesServiceServer).Create(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MoviesService_Create_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MoviesServiceServer).Create(ctx, req.(*CreateMovieRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MoviesService_Update_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateMovieRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MoviesServiceServer).Update(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MoviesService_Update_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MoviesServiceServer).Update(ctx, req.(*UpdateMovieRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MoviesService_Delete_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMovieRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MoviesServiceServer).Delete(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MoviesService_Delete_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MoviesServiceServer).Delete(ctx, req.(*DeleteMovieRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// MoviesService_ServiceDesc is the grpc.ServiceDesc for MoviesService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MoviesService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "movies.v1.MoviesService",
+	HandlerType: (*MoviesServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Get",
+			Handler:    _MoviesService_Get_Handler,
+		},
+		{
+			MethodName: "Create",
+			Handler:    _MoviesService_Create_Handler,
+		},
+		{
+			MethodName: "Update",
+			Handler:    _MoviesService_Update_Handler,
+		},
+		{
+			MethodName: "Delete",
+			Handler:    _MoviesService_Delete_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "List",
+			Handler:       _MoviesService_List_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "movies.proto",
+}
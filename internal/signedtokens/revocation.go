@@ -0,0 +1,35 @@
+package signedtokens
+
+import "sync"
+
+// RevocationList holds ids of sessions which were revoked
+// before their tokens expired. It is kept in memory and
+// periodically replaced by list from DB
+type RevocationList struct {
+	mu  sync.RWMutex
+	ids map[int64]bool
+}
+
+func NewRevocationList() *RevocationList {
+	return &RevocationList{ids: make(map[int64]bool)}
+}
+
+func (l *RevocationList) IsRevoked(sessionID int64) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.ids[sessionID]
+}
+
+func (l *RevocationList) Replace(sessionIDs []int64) {
+	ids := make(map[int64]bool, len(sessionIDs))
+
+	for _, id := range sessionIDs {
+		ids[id] = true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.ids = ids
+}
@@ -0,0 +1,189 @@
+package signedtokens
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const issuer = "movies-api"
+
+var ErrInvalidToken = errors.New("invalid signed token")
+
+// Claims are carried by signed token, so authentication
+// does not need to look up user and permissions in DB
+type Claims struct {
+	jwt.RegisteredClaims
+	Activated   bool     `json:"activated"`
+	Permissions []string `json:"permissions"`
+}
+
+func (c *Claims) UserID() int64 {
+	id, _ := strconv.ParseInt(c.Subject, 10, 64)
+	return id
+}
+
+// SessionID is id of authentication token row
+// which was created together with signed token
+func (c *Claims) SessionID() int64 {
+	id, _ := strconv.ParseInt(c.ID, 10, 64)
+	return id
+}
+
+type Key struct {
+	ID         string
+	privateKey ed25519.PrivateKey
+}
+
+// ParseKeys parses space separated "kid=seed" pairs, where
+// seed is base64 encoded 32 bytes Ed25519 seed
+func ParseKeys(s string) ([]Key, error) {
+	keys := []Key{}
+
+	for _, pair := range strings.Fields(s) {
+		id, encoded, ok := strings.Cut(pair, "=")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("signing key %q must be in kid=seed format", pair)
+		}
+
+		seed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %s: %w", id, err)
+		}
+
+		if len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("signing key %s: seed must be %d bytes", id, ed25519.SeedSize)
+		}
+
+		keys = append(keys, Key{ID: id, privateKey: ed25519.NewKeyFromSeed(seed)})
+	}
+
+	return keys, nil
+}
+
+// GenerateKey creates random key, tokens signed
+// by it can not be verified after restart
+func GenerateKey(id string) (Key, error) {
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return Key{}, err
+	}
+
+	return Key{ID: id, privateKey: privateKey}, nil
+}
+
+// Signer signs tokens with its first key and verifies them
+// with any of its keys. New key is rotated in by putting it first,
+// old one is kept until tokens signed by it expire
+type Signer struct {
+	keys []Key
+}
+
+func NewSigner(keys []Key) (*Signer, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("signer needs at least one key")
+	}
+
+	seen := make(map[string]bool)
+
+	for _, key := range keys {
+		if seen[key.ID] {
+			return nil, fmt.Errorf("duplicate signing key id %s", key.ID)
+		}
+
+		seen[key.ID] = true
+	}
+
+	return &Signer{keys: keys}, nil
+}
+
+func (s *Signer) Sign(userID, sessionID int64, activated bool, permissions []string, expiry time.Time) (string, error) {
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   strconv.FormatInt(userID, 10),
+			ID:        strconv.FormatInt(sessionID, 10),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(expiry),
+		},
+		Activated:   activated,
+		Permissions: permissions,
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = s.keys[0].ID
+
+	return token.SignedString(s.keys[0].privateKey)
+}
+
+// Verify checks signature and expiry of token. Revocation
+// is checked by caller against its RevocationList
+func (s *Signer) Verify(tokenString string) (*Claims, error) {
+	claims := &Claims{}
+
+	_, err := jwt.ParseWithClaims(tokenString, claims, s.keyFunc, jwt.WithValidMethods([]string{jwt.SigningMethodEdDSA.Alg()}), jwt.WithIssuer(issuer))
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+
+	// parser accepts tokens without expiry
+	if claims.ExpiresAt == nil || claims.UserID() < 1 || claims.SessionID() < 1 {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+func (s *Signer) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	for _, key := range s.keys {
+		if key.ID == kid {
+			return key.privateKey.Public(), nil
+		}
+	}
+
+	return nil, fmt.Errorf("unknown key id %q", kid)
+}
+
+// JWK is public key in JSON Web Key format (RFC 8037)
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Curve     string `json:"crv"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	X         string `json:"x"`
+}
+
+// JWKS returns public keys for services which verify tokens themselves
+func (s *Signer) JWKS() []JWK {
+	keys := make([]JWK, 0, len(s.keys))
+
+	for _, key := range s.keys {
+		keys = append(keys, JWK{
+			KeyType:   "OKP",
+			Curve:     "Ed25519",
+			KeyID:     key.ID,
+			Use:       "sig",
+			Algorithm: jwt.SigningMethodEdDSA.Alg(),
+			X:         base64.RawURLEncoding.EncodeToString(key.privateKey.Public().(ed25519.PublicKey)),
+		})
+	}
+
+	return keys
+}
+
+// IsSigned tells signed token from opaque one
+// by its header.payload.signature form
+func IsSigned(token string) bool {
+	return strings.Count(token, ".") == 2
+}
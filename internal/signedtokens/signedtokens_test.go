@@ -0,0 +1,296 @@
+package signedtokens
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newTestSigner(t *testing.T, ids ...string) (*Signer, []Key) {
+	t.Helper()
+
+	keys := make([]Key, 0, len(ids))
+
+	for _, id := range ids {
+		key, err := GenerateKey(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		keys = append(keys, key)
+	}
+
+	signer, err := NewSigner(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signer, keys
+}
+
+// signClaims signs arbitrary claims with key, so tests
+// can build tokens which Sign never produces
+func signClaims(t *testing.T, key Key, claims jwt.Claims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodEdDSA, claims)
+	token.Header["kid"] = key.ID
+
+	signed, err := token.SignedString(key.privateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return signed
+}
+
+func TestSignVerify(t *testing.T) {
+	signer, _ := newTestSigner(t, "k1")
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	token, err := signer.Sign(42, 7, true, []string{"movies:read", "movies:write"}, expiry)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !IsSigned(token) {
+		t.Errorf("want signed token, got %q", token)
+	}
+
+	claims, err := signer.Verify(token)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if claims.UserID() != 42 || claims.SessionID() != 7 || !claims.Activated {
+		t.Errorf("unexpected claims %+v", claims)
+	}
+
+	if strings.Join(claims.Permissions, " ") != "movies:read movies:write" {
+		t.Errorf("want permissions movies:read movies:write, got %v", claims.Permissions)
+	}
+
+	if !claims.ExpiresAt.Time.Equal(expiry) {
+		t.Errorf("want expiry %s, got %s", expiry, claims.ExpiresAt.Time)
+	}
+}
+
+func TestVerifyRejectsExpired(t *testing.T) {
+	signer, _ := newTestSigner(t, "k1")
+
+	token, err := signer.Sign(42, 7, true, nil, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := signer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("want ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestVerifyRotatedKey(t *testing.T) {
+	oldSigner, keys := newTestSigner(t, "old")
+
+	token, err := oldSigner.Sign(42, 7, true, nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	newKey, err := GenerateKey("new")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// new key signs, old one only verifies
+	rotated, err := NewSigner([]Key{newKey, keys[0]})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := rotated.Verify(token); err != nil {
+		t.Errorf("token of rotated key must verify: %s", err)
+	}
+
+	newToken, err := rotated.Sign(42, 8, true, nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if kid := parsed.Header["kid"]; kid != "new" {
+		t.Errorf("want token signed by first key, got kid %v", kid)
+	}
+}
+
+func TestVerifyRejectsUnknownKey(t *testing.T) {
+	signer, _ := newTestSigner(t, "k1")
+	other, _ := newTestSigner(t, "k2")
+
+	token, err := other.Sign(42, 7, true, nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := signer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("want ErrInvalidToken, got %v", err)
+	}
+
+	// key id of verifier with signature of other key
+	forged, _ := newTestSigner(t, "k1")
+
+	token, err = forged.Sign(42, 7, true, nil, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := signer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("want ErrInvalidToken for wrong signature, got %v", err)
+	}
+}
+
+func TestVerifyRejectsOtherAlgorithms(t *testing.T) {
+	signer, keys := newTestSigner(t, "k1")
+
+	claims := Claims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    issuer,
+			Subject:   "42",
+			ID:        "7",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}
+
+	hs256 := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	hs256.Header["kid"] = "k1"
+
+	// HMAC keyed with public key is classic algorithm confusion
+	hmacToken, err := hs256.SignedString([]byte(keys[0].privateKey.Public().(ed25519.PublicKey)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	none := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	none.Header["kid"] = "k1"
+
+	noneToken, err := none.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, token := range map[string]string{"HS256": hmacToken, "none": noneToken} {
+		if _, err := signer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: want ErrInvalidToken, got %v", name, err)
+		}
+	}
+}
+
+func TestVerifyRejectsInvalidClaims(t *testing.T) {
+	signer, keys := newTestSigner(t, "k1")
+	expiry := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	tests := []struct {
+		name   string
+		claims jwt.RegisteredClaims
+	}{
+		{"without exp", jwt.RegisteredClaims{Issuer: issuer, Subject: "42", ID: "7"}},
+		{"zero sub", jwt.RegisteredClaims{Issuer: issuer, Subject: "0", ID: "7", ExpiresAt: expiry}},
+		{"negative sub", jwt.RegisteredClaims{Issuer: issuer, Subject: "-1", ID: "7", ExpiresAt: expiry}},
+		{"text sub", jwt.RegisteredClaims{Issuer: issuer, Subject: "admin", ID: "7", ExpiresAt: expiry}},
+		{"zero jti", jwt.RegisteredClaims{Issuer: issuer, Subject: "42", ID: "0", ExpiresAt: expiry}},
+		{"negative jti", jwt.RegisteredClaims{Issuer: issuer, Subject: "42", ID: "-7", ExpiresAt: expiry}},
+		{"other issuer", jwt.RegisteredClaims{Issuer: "other", Subject: "42", ID: "7", ExpiresAt: expiry}},
+	}
+
+	for _, tt := range tests {
+		token := signClaims(t, keys[0], Claims{RegisteredClaims: tt.claims})
+
+		if _, err := signer.Verify(token); !errors.Is(err, ErrInvalidToken) {
+			t.Errorf("%s: want ErrInvalidToken, got %v", tt.name, err)
+		}
+	}
+}
+
+// TestJWKS checks key of RFC 8037 Appendix A.1
+func TestJWKS(t *testing.T) {
+	seed, err := base64.RawURLEncoding.DecodeString("nWGxne_9WmC6hEr0kuwsxERJxWl7MmkZcDusAxyuf2A")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keys, err := ParseKeys("rfc=" + base64.StdEncoding.EncodeToString(seed))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := NewSigner(keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwks := signer.JWKS()
+
+	if len(jwks) != 1 {
+		t.Fatalf("want 1 key, got %d", len(jwks))
+	}
+
+	want := JWK{
+		KeyType:   "OKP",
+		Curve:     "Ed25519",
+		KeyID:     "rfc",
+		Use:       "sig",
+		Algorithm: "EdDSA",
+		X:         "11qYAYKxCrfVS_7TyWQHOg7hcvPapiMlrwIaaPcHURo",
+	}
+
+	if jwks[0] != want {
+		t.Errorf("want %+v, got %+v", want, jwks[0])
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	seed := base64.StdEncoding.EncodeToString(make([]byte, 32))
+
+	keys, err := ParseKeys("  k1=" + seed + "\tk2=" + seed + " ")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 2 || keys[0].ID != "k1" || keys[1].ID != "k2" {
+		t.Errorf("unexpected keys %+v", keys)
+	}
+
+	if keys, err := ParseKeys(""); err != nil || len(keys) != 0 {
+		t.Errorf("want no keys, got %v, %v", keys, err)
+	}
+
+	invalid := map[string]string{
+		"without separator": "k1" + seed,
+		"empty kid":         "=" + seed,
+		"not base64":        "k1=not-base64!",
+		"short seed":        "k1=" + base64.StdEncoding.EncodeToString(make([]byte, 16)),
+		"long seed":         "k1=" + base64.StdEncoding.EncodeToString(make([]byte, 64)),
+	}
+
+	for name, s := range invalid {
+		if _, err := ParseKeys(s); err == nil {
+			t.Errorf("%s: want error", name)
+		}
+	}
+
+	if _, err := NewSigner(append(keys, keys[0])); err == nil {
+		t.Error("want error for duplicate key id")
+	}
+
+	if _, err := NewSigner(nil); err == nil {
+		t.Error("want error for signer without keys")
+	}
+}
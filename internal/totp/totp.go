@@ -0,0 +1,124 @@
+// Package totp implements time-based one-time passwords (RFC 6238)
+// with parameters authenticator apps expect: HMAC-SHA1,
+// 6 digits and 30 seconds period
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	Digits = 6
+	Period = 30
+	// Skew is number of periods before and after current one
+	// which codes are accepted, so clock drift is tolerated
+	Skew = 1
+	// secretLength is length of HMAC-SHA1 output recommended by RFC 4226
+	secretLength = 20
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateSecret() ([]byte, error) {
+	secret := make([]byte, secretLength)
+
+	_, err := rand.Read(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	return secret, nil
+}
+
+// EncodeSecret returns secret in base32 form for manual entry
+func EncodeSecret(secret []byte) string {
+	return encoding.EncodeToString(secret)
+}
+
+// ProvisioningURI returns otpauth URI which authenticator
+// apps read from QR code
+func ProvisioningURI(secret []byte, issuer, account string) string {
+	params := url.Values{}
+	params.Set("secret", EncodeSecret(secret))
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(Digits))
+	params.Set("period", fmt.Sprint(Period))
+
+	u := url.URL{
+		Scheme: "otpauth",
+		Host:   "totp",
+		Path:   "/" + issuer + ":" + account,
+		// some apps do not decode "+" as space in query
+		RawQuery: strings.ReplaceAll(params.Encode(), "+", "%20"),
+	}
+
+	return u.String()
+}
+
+// Counter returns number of period which t belongs to
+func Counter(t time.Time) int64 {
+	return t.Unix() / Period
+}
+
+// Code returns code of secret at time t
+func Code(secret []byte, t time.Time) string {
+	return generate(secret, Counter(t), Digits, sha1.New)
+}
+
+// Validate checks code of time t and returns counter it was generated
+// for. Codes of counters up to lastCounter were already used and
+// are rejected, so code can not be replayed
+func Validate(secret []byte, code string, t time.Time, lastCounter int64) (int64, bool) {
+	if len(code) != Digits {
+		return 0, false
+	}
+
+	current := Counter(t)
+
+	for counter := current - Skew; counter <= current+Skew; counter++ {
+		if counter <= lastCounter {
+			continue
+		}
+
+		expected := generate(secret, counter, Digits, sha1.New)
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter, true
+		}
+	}
+
+	return 0, false
+}
+
+// generate is HOTP of RFC 4226, time based codes use
+// number of period as counter
+func generate(secret []byte, counter int64, digits int, alg func() hash.Hash) string {
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(alg, secret)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// dynamic truncation
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	mod := uint32(1)
+	for i := 0; i < digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", digits, value%mod)
+}
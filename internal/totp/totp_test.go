@@ -0,0 +1,121 @@
+package totp
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"hash"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestGenerateRFC6238 checks codes against test vectors
+// of RFC 6238 Appendix B
+func TestGenerateRFC6238(t *testing.T) {
+	seeds := map[string]struct {
+		secret []byte
+		alg    func() hash.Hash
+	}{
+		"SHA1":   {[]byte("12345678901234567890"), sha1.New},
+		"SHA256": {[]byte("12345678901234567890123456789012"), sha256.New},
+		"SHA512": {[]byte("1234567890123456789012345678901234567890123456789012345678901234"), sha512.New},
+	}
+
+	tests := []struct {
+		unix int64
+		mode string
+		want string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111109, "SHA256", "68084774"},
+		{1111111109, "SHA512", "25091201"},
+		{1111111111, "SHA1", "14050471"},
+		{1111111111, "SHA256", "67062674"},
+		{1111111111, "SHA512", "99943326"},
+		{1234567890, "SHA1", "89005924"},
+		{1234567890, "SHA256", "91819424"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{2000000000, "SHA256", "90698825"},
+		{2000000000, "SHA512", "38618901"},
+		{20000000000, "SHA1", "65353130"},
+		{20000000000, "SHA256", "77737706"},
+		{20000000000, "SHA512", "47863826"},
+	}
+
+	for _, tt := range tests {
+		seed := seeds[tt.mode]
+		counter := Counter(time.Unix(tt.unix, 0))
+
+		got := generate(seed.secret, counter, 8, seed.alg)
+
+		if got != tt.want {
+			t.Errorf("%s at %d: want %s, got %s", tt.mode, tt.unix, tt.want, got)
+		}
+	}
+}
+
+func TestCode(t *testing.T) {
+	secret := []byte("12345678901234567890")
+
+	// last 6 digits of SHA1 vector at 1111111109
+	if got := Code(secret, time.Unix(1111111109, 0)); got != "081804" {
+		t.Errorf("want 081804, got %s", got)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+	current := Counter(now)
+
+	tests := []struct {
+		name        string
+		code        string
+		lastCounter int64
+		wantCounter int64
+		wantOk      bool
+	}{
+		{"current", Code(secret, now), 0, current, true},
+		{"previous period", Code(secret, now.Add(-Period*time.Second)), 0, current - 1, true},
+		{"next period", Code(secret, now.Add(Period*time.Second)), 0, current + 1, true},
+		{"outside skew", Code(secret, now.Add(-2*Period*time.Second)), 0, 0, false},
+		{"replayed", Code(secret, now), current, 0, false},
+		{"wrong code", "000000", 0, 0, false},
+		{"wrong length", "0818040", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		counter, ok := Validate(secret, tt.code, now, tt.lastCounter)
+
+		if ok != tt.wantOk || counter != tt.wantCounter {
+			t.Errorf("%s: want (%d, %t), got (%d, %t)", tt.name, tt.wantCounter, tt.wantOk, counter, ok)
+		}
+	}
+}
+
+func TestProvisioningURI(t *testing.T) {
+	secret := []byte("12345678901234567890")
+
+	u, err := url.Parse(ProvisioningURI(secret, "Movies API", "alice@example.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if u.Scheme != "otpauth" || u.Host != "totp" || u.Path != "/Movies API:alice@example.com" {
+		t.Errorf("unexpected uri %s", u)
+	}
+
+	if got := u.Query().Get("secret"); got != "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ" {
+		t.Errorf("want base32 secret, got %s", got)
+	}
+
+	if got := u.Query().Get("issuer"); got != "Movies API" || strings.Contains(u.RawQuery, "+") {
+		t.Errorf("want issuer Movies API, got %s", got)
+	}
+}
@@ -1,6 +1,7 @@
 package utils
 
 import (
+	"crypto/sha256"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -17,12 +18,16 @@ import (
 type Envelope map[string]any
 
 func ReadIdParam(r *http.Request) (int64, error) {
-	strId := chi.URLParam(r, "id")
+	return ReadInt64Param(r, "id")
+}
+
+func ReadInt64Param(r *http.Request, key string) (int64, error) {
+	strId := chi.URLParam(r, key)
 
 	id, err := strconv.ParseInt(strId, 10, 64)
 
 	if err != nil || id < 1 {
-		return 0, errors.New("invalid id")
+		return 0, fmt.Errorf("invalid %s", key)
 	}
 
 	return id, nil
@@ -39,12 +44,44 @@ func WriteJSON(w http.ResponseWriter, status int, data Envelope, headers http.He
 		w.Header()[key] = value
 	}
 
-	w.Header().Add("Content-Type", "application/json")
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/json")
+	}
 	w.WriteHeader(status)
 	w.Write(res)
 	return nil
 }
 
+// bad request codes are part of API contract like validation codes,
+// clients get them localized by "error.<code>" catalogue keys
+const (
+	CodeBadJSON            = "bad_json"
+	CodeBadJSONAt          = "bad_json_at"
+	CodeJSONType           = "json_type"
+	CodeJSONTypeAt         = "json_type_at"
+	CodeEmptyBody          = "empty_body"
+	CodeUnknownKey         = "unknown_key"
+	CodeBodyTooLarge       = "body_too_large"
+	CodeMultipleJSONValues = "multiple_json_values"
+	CodeMissingColumns     = "missing_columns"
+)
+
+// BadRequestError is error of malformed request. Code with params
+// localizes message, Error returns message in default locale
+type BadRequestError struct {
+	Code   string
+	Params map[string]any
+	msg    string
+}
+
+func NewBadRequestError(code string, params map[string]any, msg string) *BadRequestError {
+	return &BadRequestError{Code: code, Params: params, msg: msg}
+}
+
+func (e *BadRequestError) Error() string {
+	return e.msg
+}
+
 func ReadJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	maxBytes := 1048756 // 1 MB
 	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
@@ -62,26 +99,31 @@ func ReadJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 
 		switch {
 		case errors.As(err, &syntaxError):
-			return fmt.Errorf("body contains bad JSON (at character %d)", syntaxError.Offset)
+			return NewBadRequestError(CodeBadJSONAt, map[string]any{"offset": syntaxError.Offset},
+				fmt.Sprintf("body contains bad JSON (at character %d)", syntaxError.Offset))
 
 		case errors.Is(err, io.ErrUnexpectedEOF):
-			return errors.New("body contains bad JSON")
+			return NewBadRequestError(CodeBadJSON, nil, "body contains bad JSON")
 
 		case errors.As(err, &unmarshalTypeError):
 			if unmarshalTypeError.Field != "" {
-				return fmt.Errorf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field)
+				return NewBadRequestError(CodeJSONType, map[string]any{"field": unmarshalTypeError.Field},
+					fmt.Sprintf("body contains incorrect JSON type for field %q", unmarshalTypeError.Field))
 			}
-			return fmt.Errorf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset)
+			return NewBadRequestError(CodeJSONTypeAt, map[string]any{"offset": unmarshalTypeError.Offset},
+				fmt.Sprintf("body contains incorrect JSON type (at character %d)", unmarshalTypeError.Offset))
 
 		case errors.Is(err, io.EOF):
-			return errors.New("body cant be empty")
+			return NewBadRequestError(CodeEmptyBody, nil, "body cant be empty")
 
-		case strings.HasPrefix(err.Error(), "json: Unkown field"):
-			fieldName := strings.TrimPrefix(err.Error(), "json: Unknown field")
-			return fmt.Errorf("body contains unknown key %s", fieldName)
+		case strings.HasPrefix(err.Error(), "json: unknown field "):
+			fieldName := strings.TrimPrefix(err.Error(), "json: unknown field ")
+			return NewBadRequestError(CodeUnknownKey, map[string]any{"key": fieldName},
+				fmt.Sprintf("body contains unknown key %s", fieldName))
 
 		case errors.As(err, &maxBytesError):
-			return fmt.Errorf("body must be less than %d bytes", maxBytesError.Limit)
+			return NewBadRequestError(CodeBodyTooLarge, map[string]any{"bytes": maxBytesError.Limit},
+				fmt.Sprintf("body must be less than %d bytes", maxBytesError.Limit))
 
 		case errors.As(err, &invalidUnmarshalError):
 			panic(err)
@@ -94,7 +136,7 @@ func ReadJSON(w http.ResponseWriter, r *http.Request, dst any) error {
 	err = decoder.Decode(&struct{}{})
 
 	if err != io.EOF {
-		return errors.New("body must only contain a single JSON value")
+		return NewBadRequestError(CodeMultipleJSONValues, nil, "body must only contain a single JSON value")
 	}
 
 	return nil
@@ -130,9 +172,61 @@ func ReadInt(queries url.Values, key string, defaultValue int, v *validator.Vali
 	i, err := strconv.Atoi(s)
 
 	if err != nil {
-		v.AddError(key, "must be an integer value")
+		v.AddCode(key, validator.CodeType, validator.Params{"type": "integer"}, "must be an integer value")
 		return defaultValue
 	}
 
 	return i
 }
+
+func ReadBool(queries url.Values, key string, defaultValue bool, v *validator.Validator) bool {
+	s := queries.Get(key)
+
+	if s == "" {
+		return defaultValue
+	}
+
+	b, err := strconv.ParseBool(s)
+
+	if err != nil {
+		v.AddCode(key, validator.CodeType, validator.Params{"type": "boolean"}, "must be a boolean value")
+		return defaultValue
+	}
+
+	return b
+}
+
+// ContentETag formats strong entity tag of representation whose
+// content may change without version bump, e.g. because of related data
+func ContentETag(version int32, data any) (string, error) {
+	js, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256(js)
+
+	return fmt.Sprintf(`"%d-%x"`, version, sum[:8]), nil
+}
+
+// MatchETag reports whether If-Match or If-None-Match header value
+// matches etag. Weak comparison ignores W/ prefix of header tags
+func MatchETag(header, etag string, weak bool) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if tag == "*" {
+			return true
+		}
+
+		if weak {
+			tag = strings.TrimPrefix(tag, "W/")
+		}
+
+		if tag == etag {
+			return true
+		}
+	}
+
+	return false
+}
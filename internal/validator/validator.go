@@ -6,27 +6,87 @@ var (
 	EmailRegex = regexp.MustCompile("^[a-zA-Z0-9.!#$%&'*+\\/=?^_`{|}~-]+@[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?(?:\\.[a-zA-Z0-9](?:[a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?)*$")
 )
 
+// error codes are part of API contract, clients
+// use them to localize messages, so existing values must never change
+const (
+	CodeInvalid   = "invalid"
+	CodeRequired  = "required"
+	CodeType      = "type"
+	CodeFormat    = "format"
+	CodeMinLength = "min_length"
+	CodeMaxLength = "max_length"
+	CodeLength    = "length"
+	CodeMin       = "min"
+	CodeMax       = "max"
+	CodeMinItems  = "min_items"
+	CodeMaxItems  = "max_items"
+	CodeLteField  = "lte_field"
+	CodeOneOf     = "one_of"
+	CodeUnique    = "unique"
+	CodeFuture    = "future"
+	CodeNotFuture = "not_future"
+	CodeMismatch  = "mismatch"
+	CodeConflicts = "conflicts"
+)
+
+// codes of checks which handlers do against stored data
+const (
+	CodeInvalidToken     = "invalid_token"
+	CodeInvalidMFACode   = "invalid_mfa_code"
+	CodeMovieNotFound    = "movie_not_found"
+	CodeEmailNotFound    = "email_not_found"
+	CodeAlreadyReviewed  = "already_reviewed"
+	CodeDuplicateRole    = "duplicate_role"
+	CodeInactiveAccount  = "inactive_account"
+	CodeAlreadyActivated = "already_activated"
+	CodeMFAEnabled       = "mfa_enabled"
+	CodeMFANotStarted    = "mfa_not_started"
+)
+
+// Params holds values of the rule which failed, e.g. {"max": 500}
+type Params map[string]any
+
+type Error struct {
+	Code    string `json:"code"`
+	Params  Params `json:"params,omitempty"`
+	Message string `json:"message"`
+}
+
+// Errors holds all failed rules of each field in order they were checked
+type Errors map[string][]Error
+
+// Messages returns first message of each field
+func (e Errors) Messages() map[string]string {
+	messages := make(map[string]string, len(e))
+
+	for key, errs := range e {
+		if len(errs) > 0 {
+			messages[key] = errs[0].Message
+		}
+	}
+
+	return messages
+}
+
 type Validator struct {
-	Errors map[string]string
+	Errors Errors
 }
 
 func New() *Validator {
-	return &Validator{Errors: make(map[string]string)}
+	return &Validator{Errors: make(Errors)}
 }
 
 func (v *Validator) Valid() bool {
 	return len(v.Errors) == 0
 }
 
-func (v *Validator) AddError(key, msg string) {
-	if _, exists := v.Errors[key]; !exists {
-		v.Errors[key] = msg
-	}
+func (v *Validator) AddCode(key, code string, params Params, msg string) {
+	v.Errors[key] = append(v.Errors[key], Error{Code: code, Params: params, Message: msg})
 }
 
-func (v *Validator) Check(ok bool, key, msg string) {
+func (v *Validator) CheckCode(ok bool, key, code string, params Params, msg string) {
 	if !ok {
-		v.AddError(key, msg)
+		v.AddCode(key, code, params, msg)
 	}
 }
 
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS people;
\ No newline at end of file
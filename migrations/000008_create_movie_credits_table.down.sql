@@ -0,0 +1 @@
+DROP TABLE IF EXISTS movie_credits;
\ No newline at end of file
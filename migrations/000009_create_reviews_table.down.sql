@@ -0,0 +1,8 @@
+DELETE FROM permissions WHERE code = 'reviews:write';
+
+DROP TABLE IF EXISTS reviews;
+
+DROP INDEX IF EXISTS movies_rating_idx;
+
+ALTER TABLE movies DROP COLUMN IF EXISTS votes;
+ALTER TABLE movies DROP COLUMN IF EXISTS rating;
\ No newline at end of file
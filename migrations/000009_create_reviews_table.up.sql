@@ -0,0 +1,27 @@
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS rating double precision NOT NULL DEFAULT 0;
+ALTER TABLE movies ADD COLUMN IF NOT EXISTS votes integer NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS reviews (
+    id bigserial PRIMARY KEY,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE,
+    rating integer NOT NULL,
+    body text NOT NULL DEFAULT '',
+    version integer NOT NULL DEFAULT 1,
+    UNIQUE (movie_id, user_id)
+);
+
+ALTER TABLE reviews ADD CONSTRAINT reviews_rating_check CHECK (rating BETWEEN 1 AND 10);
+
+CREATE INDEX IF NOT EXISTS movies_rating_idx ON movies (rating);
+
+INSERT INTO permissions (code)
+VALUES ('reviews:write');
+
+-- existing readers are allowed to review
+INSERT INTO users_permissions
+SELECT users_permissions.user_id, (SELECT id FROM permissions WHERE code = 'reviews:write')
+FROM users_permissions
+INNER JOIN permissions ON permissions.id = users_permissions.permission_id
+WHERE permissions.code = 'movies:read';
\ No newline at end of file
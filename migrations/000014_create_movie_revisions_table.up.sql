@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS movie_revisions (
+    movie_id bigint NOT NULL REFERENCES movies ON DELETE CASCADE,
+    version integer NOT NULL,
+    created_at timestamp(0) with time zone NOT NULL DEFAULT NOW(),
+    title text NOT NULL,
+    year integer NOT NULL,
+    runtime integer NOT NULL,
+    genres text[] NOT NULL,
+    editor_id bigint REFERENCES users ON DELETE SET NULL,
+    PRIMARY KEY (movie_id, version)
+);
+
+-- current state of existing movies becomes their first known revision
+INSERT INTO movie_revisions (movie_id, version, created_at, title, year, runtime, genres)
+SELECT id, version, created_at, title, year, runtime, genres
+FROM movies;
\ No newline at end of file
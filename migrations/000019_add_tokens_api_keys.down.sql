@@ -0,0 +1,4 @@
+DELETE FROM tokens WHERE scope = 'api-key';
+ALTER TABLE tokens DROP COLUMN IF EXISTS permissions;
+ALTER TABLE tokens DROP COLUMN IF EXISTS name;
+ALTER TABLE tokens ALTER COLUMN expiry SET NOT NULL;
\ No newline at end of file
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS users_recovery_codes;
+DROP TABLE IF EXISTS users_totp;
+DELETE FROM tokens WHERE scope = 'mfa-pending';
\ No newline at end of file
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS users_totp (
+    user_id bigint PRIMARY KEY REFERENCES users ON DELETE CASCADE,
+    secret bytea NOT NULL,
+    confirmed_at timestamp(0) with time zone,
+    last_counter bigint NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS users_recovery_codes (
+    hash bytea PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES users ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS users_recovery_codes_user_id_idx ON users_recovery_codes (user_id);
\ No newline at end of file